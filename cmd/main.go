@@ -4,44 +4,218 @@ import (
 	"encoding/xml"
 	"flag"
 	"fmt"
+	"image"
+	"image/png"
 	"io/ioutil"
 	"log"
 	"os"
+	"path/filepath"
+	"regexp"
 	"strings"
 
 	"github.com/j6s/lightroom2aftershot/lib"
 )
 
+// Files that contain the develop settings of a photo as embedded XMP
+var photoExtensions = map[string]bool{
+	".jpg":  true,
+	".jpeg": true,
+	".tif":  true,
+	".tiff": true,
+	".dng":  true,
+}
+
 func main() {
+	photo := flag.Bool("photo", false, "Treat the input as an edited photo (XMP sidecar, JPEG, DNG or TIFF) instead of a preset")
+	keep := flag.String("keep", "", "Comma separated per-image settings to keep when converting a photo: crop,whitebalance,local,spots")
+	catalog := flag.Bool("catalog", false, "Treat the input as a lightroom catalog (.lrcat) and convert all develop presets, snapshots and virtual copies in it")
+	list := flag.Bool("list", false, "Only list the develop settings found in the catalog")
+	out := flag.String("out", ".", "Directory to write the presets converted from a catalog to")
+	target := flag.String("target", lib.AFTERSHOT_DEFAULT_TARGET, "AfterShot version to generate presets for: as3|asp2|asp3")
+	mode := flag.String("mode", lib.PRESET_MODE_ADDITIVE, "additive: only write the changed options, reset: write neutral values for all other options")
+	noGeometry := flag.Bool("no-geometry", false, "Leave out perspective and rotation of the lightroom preset")
+	grainOverlay := flag.String("grain-overlay", "", "PNG file to write the grain texture to, which has to be added manually as an overlay layer")
 	flag.Parse()
 	if flag.NArg() != 1 {
 		log.Printf("[ERROR] Must specify exactly 1 file to convert. %d specified", flag.NArg())
-		log.Printf("[ERROR] Usage: lightroom2aftershot lightroompreset.xml > aftershotpreset.xml")
+		log.Printf("[ERROR] Usage: lightroom2aftershot [-photo] lightroompreset.xml > aftershotpreset.xml")
+		log.Printf("[ERROR]        lightroom2aftershot -catalog [-list] [-out directory] catalog.lrcat")
 		os.Exit(1)
 	}
 
+	var err error
+	options := lib.NewConversionOptions()
+	options.Target, err = lib.NewAfterShotTarget(*target)
+	if err != nil {
+		log.Fatalf("[ERROR] %s", err)
+	}
+	if *mode != lib.PRESET_MODE_ADDITIVE && *mode != lib.PRESET_MODE_RESET {
+		log.Fatalf("[ERROR] Unknown preset mode '%s'", *mode)
+	}
+	options.Mode = *mode
+	options.ExcludeGeometry = *noGeometry
+	options.IncludeCrop = photoSettingsPolicy(*keep).KeepCrop
+
+	extension := strings.ToLower(filepath.Ext(flag.Arg(0)))
+	if *catalog || extension == ".lrcat" {
+		convertCatalog(flag.Arg(0), *out, *list, photoSettingsPolicy(*keep), options)
+		return
+	}
+
 	fileContents, err := ioutil.ReadFile(flag.Arg(0))
 	if err != nil {
 		log.Printf("Error while reading file")
 		log.Fatal(err)
 	}
 
+	// Images can only be photos with embedded XMP, everything else is a preset unless -photo is given
+	if photoExtensions[extension] {
+		*photo = true
+	}
+
 	preset := lib.NewLightroomPreset()
-	err = xml.Unmarshal(fileContents, &preset)
+	if *photo {
+		preset, err = lib.NewLightroomPresetFromPhoto(fileContents, photoSettingsPolicy(*keep))
+	} else {
+		err = xml.Unmarshal(fileContents, &preset)
+	}
 	if err != nil {
 		log.Printf("Error while unmarshalling")
 		log.Fatal(err)
 	}
 
-	aftershot := lib.NewAftershotPresetFromLightroom(preset)
+	aftershotPreset := lib.NewAftershotPresetFromLightroomWithOptions(preset, options)
+	_, err = aftershotPreset.WriteTo(os.Stdout)
+	if err != nil {
+		log.Printf("Error while writing preset")
+		log.Fatal(err)
+	}
+
+	if aftershotPreset.GrainOverlay != nil {
+		if *grainOverlay == "" {
+			log.Printf("[WARN] The preset uses grain, which is not converted. Use -grain-overlay to write the grain texture and add it manually as an overlay layer.")
+			return
+		}
+
+		err = writeGrainOverlay(*grainOverlay, aftershotPreset.GrainOverlay)
+		if err != nil {
+			log.Printf("Error while writing grain texture")
+			log.Fatal(err)
+		}
+	}
+}
 
-	xml, err := xml.MarshalIndent(aftershot, "", "    ")
+func convertCatalog(path string, outputDirectory string, listOnly bool, policy lib.PhotoSettingsPolicy, options lib.ConversionOptions) {
+	entries, err := lib.ReadLightroomCatalog(path, policy)
 	if err != nil {
+		log.Printf("Error while reading catalog")
 		log.Fatal(err)
 	}
 
-	// Just a rough estimate of indentation to have the options layed out nicer in the file
-	prettyXml := strings.Replace(string(xml), "bopt:", "\n                                        bopt:", -1)
+	// Snapshots of different photos often share names (e.g. 'Final'), so names are
+	// made unique in order not to overwrite earlier presets of the same run
+	usedNames := map[string]bool{}
+	for _, entry := range entries {
+		if listOnly {
+			fmt.Printf("%s\t%s\t%s\n", entry.Kind, entry.Group, entry.Name)
+			continue
+		}
+
+		baseName := uniqueFileName(catalogEntryFileName(entry), usedNames)
+		fileName := baseName + ".xmp"
+		log.Printf("[INFO] Converting %s '%s' to %s", entry.Kind, entry.Name, fileName)
+
+		preset := lib.NewAftershotPresetFromLightroomWithOptions(entry.Preset, options)
+		err = writePreset(filepath.Join(outputDirectory, fileName), preset)
+		if err != nil {
+			log.Printf("Error while writing %s", fileName)
+			log.Fatal(err)
+		}
+
+		if preset.GrainOverlay != nil {
+			err = writeGrainOverlay(filepath.Join(outputDirectory, baseName+" - grain.png"), preset.GrainOverlay)
+			if err != nil {
+				log.Printf("Error while writing grain texture of %s", fileName)
+				log.Fatal(err)
+			}
+		}
+	}
+}
+
+var unsafeCharacters = regexp.MustCompile(`[^\w\-. ]+`)
+
+// `Kind - Group - Name`, with the group (photo or preset folder) left out if there is none
+func catalogEntryFileName(entry lib.LightroomCatalogEntry) string {
+	parts := []string{entry.Kind}
+	if entry.Group != "" {
+		parts = append(parts, entry.Group)
+	}
+	parts = append(parts, entry.Name)
+
+	return unsafeCharacters.ReplaceAllString(strings.Join(parts, " - "), "_")
+}
+
+// Appends a counter to names that have already been used. Names are compared case insensitive
+// as most file systems of lightroom users are.
+func uniqueFileName(name string, used map[string]bool) string {
+	unique := name
+	for counter := 2; used[strings.ToLower(unique)]; counter++ {
+		unique = fmt.Sprintf("%s (%d)", name, counter)
+	}
+	used[strings.ToLower(unique)] = true
+	return unique
+}
+
+func writePreset(path string, preset lib.AfterShotPreset) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	_, err = preset.WriteTo(file)
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+func writeGrainOverlay(path string, texture image.Image) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	err = png.Encode(file, texture)
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	log.Printf("[INFO] Grain texture written to %s", path)
+	return file.Close()
+}
+
+func photoSettingsPolicy(keep string) lib.PhotoSettingsPolicy {
+	policy := lib.NewPhotoSettingsPolicy()
+
+	for _, category := range strings.Split(keep, ",") {
+		switch strings.TrimSpace(category) {
+		case "":
+			break
+		case "crop":
+			policy.KeepCrop = true
+		case "whitebalance":
+			policy.KeepWhiteBalance = true
+		case "local":
+			policy.KeepLocalAdjustments = true
+		case "spots":
+			policy.KeepSpotRemoval = true
+		default:
+			log.Fatalf("[ERROR] Unknown per-image setting category '%s'", category)
+		}
+	}
 
-	fmt.Printf("%s", prettyXml)
+	return policy
 }
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/j6s/lightroom2aftershot/lib"
+)
+
+func TestCatalogEntryFileName(t *testing.T) {
+	tests := []struct {
+		entry    lib.LightroomCatalogEntry
+		expected string
+	}{
+		{lib.LightroomCatalogEntry{Kind: lib.CATALOG_ENTRY_SNAPSHOT, Group: "IMG_0001.CR2", Name: "Final"}, "snapshot - IMG_0001.CR2 - Final"},
+		{lib.LightroomCatalogEntry{Kind: lib.CATALOG_ENTRY_PRESET, Group: "User Presets/B&W", Name: "Warm"}, "preset - User Presets_B_W - Warm"},
+		{lib.LightroomCatalogEntry{Kind: lib.CATALOG_ENTRY_PRESET, Name: "Warm"}, "preset - Warm"},
+	}
+
+	for _, test := range tests {
+		name := catalogEntryFileName(test.entry)
+		if name != test.expected {
+			t.Errorf("catalogEntryFileName(%+v) = '%s', expected '%s'", test.entry, name, test.expected)
+		}
+	}
+}
+
+func TestUniqueFileName(t *testing.T) {
+	used := map[string]bool{}
+	names := []string{"Final", "final", "Final", "Final (2)", "Other"}
+	expected := []string{"Final", "final (2)", "Final (3)", "Final (2) (2)", "Other"}
+
+	for index, name := range names {
+		unique := uniqueFileName(name, used)
+		if unique != expected[index] {
+			t.Errorf("uniqueFileName('%s') = '%s', expected '%s'", name, unique, expected[index])
+		}
+	}
+}
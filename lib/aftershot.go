@@ -1,85 +1,200 @@
 package lib
 
 import (
+	"bytes"
 	"encoding/xml"
+	"image"
+	"io"
 )
 
 type AfterShotPreset struct {
-	ToneCurve  AfterShotCombinedToneCurve
-	Attributes map[string]string
+	Target   AfterShotTarget
+	Settings AfterShotSettings
+
+	// Grain texture that has to be added as an overlay layer by hand, nil if the preset has no grain
+	GrainOverlay image.Image
+
+	// Layers on top of the main layer, e.g. for local adjustments
+	Layers []AfterShotLayer
+
+	// Whether applying the preset changes the geometry of the image
+	RespectsTransform bool
+
+	// Crop of the photo the preset was converted from, only included on request
+	Crop AfterShotCrop
 }
 
 func (self AfterShotPreset) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
 
-	attributes := []xml.Attr{}
-	attributes = append(attributes, self.ToneCurve.ToXmlAttributes()...)
-	for key, value := range self.Attributes {
-		attributes = append(attributes, xml.Attr{
-			Name:  xml.Name{Local: key},
-			Value: value,
-		})
+	attributes := self.Settings.ToXmlAttributes()
+
+	namespaces := []xml.Attr{
+		{Name: xml.Name{Local: "rdf:about"}, Value: ""},
+		{Name: xml.Name{Local: "xmlns:bib"}, Value: "http://www.bibblelabs.com/BibbleToplevel/5.0/"},
+		{Name: xml.Name{Local: "xmlns:bset"}, Value: "http://www.bibblelabs.com/BibbleSettings/5.0/"},
+		{Name: xml.Name{Local: "xmlns:blay"}, Value: "http://www.bibblelabs.com/BibbleLayers/5.0/"},
+		{Name: xml.Name{Local: "xmlns:bopt"}, Value: "http://www.bibblelabs.com/BibbleOpt/5.0/"},
+	}
+	if len(self.Layers) > 0 {
+		namespaces = append(namespaces, xml.Attr{Name: xml.Name{Local: "xmlns:breg"}, Value: "http://www.bibblelabs.com/BibbleRegions/5.0/"})
 	}
 
-	e.EncodeToken(xml.StartElement{
-		Name: xml.Name{Local: "x:xmpmeta"},
-		Attr: []xml.Attr{
-			{Name: xml.Name{Local: "xmlns:x"}, Value: "adobe:ns:meta/"},
-			{Name: xml.Name{Local: "x:xmptk"}, Value: "XMP Core 4.4.0"},
+	tokens := []xml.Token{
+		xml.StartElement{
+			Name: xml.Name{Local: "x:xmpmeta"},
+			Attr: []xml.Attr{
+				{Name: xml.Name{Local: "xmlns:x"}, Value: "adobe:ns:meta/"},
+				{Name: xml.Name{Local: "x:xmptk"}, Value: "XMP Core 4.4.0"},
+			},
 		},
-	})
-	e.EncodeToken(xml.StartElement{
-		Name: xml.Name{Local: "rdf:RDF"},
-		Attr: []xml.Attr{
-			{Name: xml.Name{Local: "xmlns:rdf"}, Value: "http://www.w3.org/1999/02/22-rdf-syntax-ns#"},
+		xml.StartElement{
+			Name: xml.Name{Local: "rdf:RDF"},
+			Attr: []xml.Attr{
+				{Name: xml.Name{Local: "xmlns:rdf"}, Value: "http://www.w3.org/1999/02/22-rdf-syntax-ns#"},
+			},
 		},
-	})
-	e.EncodeToken(xml.StartElement{
-		Name: xml.Name{Local: "rdf:Description"},
-		Attr: []xml.Attr{
-			{Name: xml.Name{Local: "rdf:about"}, Value: ""},
-			{Name: xml.Name{Local: "xmlns:bib"}, Value: "http://www.bibblelabs.com/BibbleToplevel/5.0/"},
-			{Name: xml.Name{Local: "xmlns:bset"}, Value: "http://www.bibblelabs.com/BibbleSettings/5.0/"},
-			{Name: xml.Name{Local: "xmlns:blay"}, Value: "http://www.bibblelabs.com/BibbleLayers/5.0/"},
-			{Name: xml.Name{Local: "xmlns:bopt"}, Value: "http://www.bibblelabs.com/BibbleOpt/5.0/"},
+		xml.StartElement{
+			Name: xml.Name{Local: "rdf:Description"},
+			Attr: namespaces,
 		},
-	})
-	e.EncodeToken(xml.StartElement{Name: xml.Name{Local: "bib:settings"}})
-
-	e.EncodeToken(xml.StartElement{
-		Name: xml.Name{Local: "rdf:Description"},
-		Attr: []xml.Attr{
-			{Name: xml.Name{Local: "bset:settingsVersion"}, Value: "66"},
-			{Name: xml.Name{Local: "bset:respectsTransfor"}, Value: "True"},
-			{Name: xml.Name{Local: "bset:curLayer"}, Value: "0"},
+		xml.StartElement{Name: xml.Name{Local: "bib:settings"}},
+
+		xml.StartElement{
+			Name: xml.Name{Local: "rdf:Description"},
+			Attr: append(
+				[]xml.Attr{
+					{Name: xml.Name{Local: "bset:settingsVersion"}, Value: self.Target.SettingsVersion},
+					{Name: xml.Name{Local: "bset:respectsTransfor"}, Value: formatFlag(self.RespectsTransform)},
+					{Name: xml.Name{Local: "bset:curLayer"}, Value: "0"},
+				},
+				self.Crop.toXmlAttributes()...,
+			),
 		},
-	})
-	e.EncodeToken(xml.StartElement{Name: xml.Name{Local: "bset:layers"}})
-	e.EncodeToken(xml.StartElement{Name: xml.Name{Local: "rdf:Seq"}})
-	e.EncodeToken(xml.StartElement{Name: xml.Name{Local: "rdf:li"}})
-	e.EncodeToken(xml.StartElement{
-		Name: xml.Name{Local: "rdf:Description"},
-		Attr: []xml.Attr{
-			{Name: xml.Name{Local: "blay:layerId"}, Value: "0"},
-			{Name: xml.Name{Local: "blay:layerPos"}, Value: "0"},
-			{Name: xml.Name{Local: "blay:name"}, Value: ""},
-			{Name: xml.Name{Local: "blay:enabled"}, Value: "True"},
+		xml.StartElement{Name: xml.Name{Local: "bset:layers"}},
+		xml.StartElement{Name: xml.Name{Local: "rdf:Seq"}},
+		xml.StartElement{Name: xml.Name{Local: "rdf:li"}},
+
+		// The main layer has no name in all versions of aftershot
+		xml.StartElement{
+			Name: xml.Name{Local: "rdf:Description"},
+			Attr: []xml.Attr{
+				{Name: xml.Name{Local: "blay:layerId"}, Value: "0"},
+				{Name: xml.Name{Local: "blay:layerPos"}, Value: "0"},
+				{Name: xml.Name{Local: "blay:name"}, Value: ""},
+				{Name: xml.Name{Local: "blay:enabled"}, Value: "True"},
+			},
+		},
+
+		xml.StartElement{
+			Name: xml.Name{Local: "blay:options"},
+			Attr: attributes,
 		},
-	})
-
-	e.EncodeElement("", xml.StartElement{
-		Name: xml.Name{Local: "blay:options"},
-		Attr: attributes,
-	})
-
-	e.EncodeToken(xml.EndElement{Name: xml.Name{Local: "rdf:Description"}})
-	e.EncodeToken(xml.EndElement{Name: xml.Name{Local: "rdf:li"}})
-	e.EncodeToken(xml.EndElement{Name: xml.Name{Local: "rdf:Seq"}})
-	e.EncodeToken(xml.EndElement{Name: xml.Name{Local: "bset:layers"}})
-	e.EncodeToken(xml.EndElement{Name: xml.Name{Local: "rdf:Description"}})
-	e.EncodeToken(xml.EndElement{Name: xml.Name{Local: "bib:settings"}})
-	e.EncodeToken(xml.EndElement{Name: xml.Name{Local: "rdf:Description"}})
-	e.EncodeToken(xml.EndElement{Name: xml.Name{Local: "rdf:RDF"}})
-	e.EncodeToken(xml.EndElement{Name: xml.Name{Local: "x:xmpmeta"}})
-
-	return nil
+		xml.EndElement{Name: xml.Name{Local: "blay:options"}},
+
+		xml.EndElement{Name: xml.Name{Local: "rdf:Description"}},
+		xml.EndElement{Name: xml.Name{Local: "rdf:li"}},
+	}
+
+	for index := range self.Layers {
+		tokens = append(tokens, self.Layers[index].toXmlTokens(index+1)...)
+	}
+
+	tokens = append(
+		tokens,
+		xml.EndElement{Name: xml.Name{Local: "rdf:Seq"}},
+		xml.EndElement{Name: xml.Name{Local: "bset:layers"}},
+		xml.EndElement{Name: xml.Name{Local: "rdf:Description"}},
+		xml.EndElement{Name: xml.Name{Local: "bib:settings"}},
+		xml.EndElement{Name: xml.Name{Local: "rdf:Description"}},
+		xml.EndElement{Name: xml.Name{Local: "rdf:RDF"}},
+		xml.EndElement{Name: xml.Name{Local: "x:xmpmeta"}},
+	)
+
+	for _, token := range tokens {
+		err := e.EncodeToken(token)
+		if err != nil {
+			return err
+		}
+	}
+
+	return e.Flush()
+}
+
+// Writes the preset as a complete XMP file
+func (self AfterShotPreset) WriteTo(writer io.Writer) (int64, error) {
+	counter := &countingWriter{writer: writer}
+
+	_, err := io.WriteString(counter, xml.Header)
+	if err != nil {
+		return counter.written, err
+	}
+
+	document := bytes.Buffer{}
+	encoder := xml.NewEncoder(&document)
+	encoder.Indent("", AFTERSHOT_XML_INDENT)
+	err = encoder.Encode(self)
+	if err != nil {
+		return counter.written, err
+	}
+
+	_, err = counter.Write(breakOptionAttributes(document.Bytes()))
+	if err != nil {
+		return counter.written, err
+	}
+
+	_, err = io.WriteString(counter, "\n")
+	return counter.written, err
+}
+
+const AFTERSHOT_XML_INDENT = "    "
+
+// encoding/xml writes all attributes of an element on one line. Options are put on a line of their
+// own, one level deeper than their element, so that presets can be read and compared line by line.
+// Only the start tags of option elements are changed, and only outside of attribute values (layer
+// names come from lightroom and may contain anything).
+func breakOptionAttributes(document []byte) []byte {
+	lines := bytes.Split(document, []byte("\n"))
+	for index, line := range lines {
+		content := bytes.TrimLeft(line, " ")
+		if !bytes.HasPrefix(content, []byte("<blay:options ")) {
+			continue
+		}
+
+		indent := line[:len(line)-len(content)]
+		broken := append([]byte{}, indent...)
+		quoted := false
+		for position := 0; position < len(content); position++ {
+			character := content[position]
+			if character == '"' {
+				quoted = !quoted
+			}
+
+			// Everything after the start tag is kept as it is
+			if character == '>' && !quoted {
+				broken = append(broken, content[position:]...)
+				break
+			}
+
+			if character == ' ' && !quoted && bytes.HasPrefix(content[position+1:], []byte("bopt:")) {
+				broken = append(broken, '\n')
+				broken = append(broken, indent...)
+				broken = append(broken, AFTERSHOT_XML_INDENT...)
+				continue
+			}
+			broken = append(broken, character)
+		}
+		lines[index] = broken
+	}
+	return bytes.Join(lines, []byte("\n"))
+}
+
+type countingWriter struct {
+	writer  io.Writer
+	written int64
+}
+
+func (self *countingWriter) Write(data []byte) (int, error) {
+	written, err := self.writer.Write(data)
+	self.written += int64(written)
+	return written, err
 }
@@ -0,0 +1,32 @@
+package lib
+
+import (
+	"encoding/xml"
+)
+
+// Crop and straightening of a photo, which are part of the settings header instead of a layer.
+// Coordinates are relative to the image as it is displayed (0 - 1), the crop is rotated around
+// its center. Positive angles rotate the image counter-clockwise.
+type AfterShotCrop struct {
+	Enabled bool
+	Left    float64
+	Top     float64
+	Right   float64
+	Bottom  float64
+	Angle   float64
+}
+
+func (self AfterShotCrop) toXmlAttributes() []xml.Attr {
+	if !self.Enabled {
+		return []xml.Attr{}
+	}
+
+	return []xml.Attr{
+		{Name: xml.Name{Local: "bset:crop"}, Value: formatFlag(self.Enabled)},
+		{Name: xml.Name{Local: "bset:cropLeft"}, Value: formatRegionNumber(self.Left)},
+		{Name: xml.Name{Local: "bset:cropTop"}, Value: formatRegionNumber(self.Top)},
+		{Name: xml.Name{Local: "bset:cropRight"}, Value: formatRegionNumber(self.Right)},
+		{Name: xml.Name{Local: "bset:cropBottom"}, Value: formatRegionNumber(self.Bottom)},
+		{Name: xml.Name{Local: "bset:rotation"}, Value: formatRegionNumber(self.Angle)},
+	}
+}
@@ -4,27 +4,30 @@ import (
 	"encoding/xml"
 	"fmt"
 	"log"
+	"math"
+	"reflect"
 	"strconv"
 	"strings"
 )
 
 /*
  * Aftershot curves XML format explained:
- * There are 5 attributes that are relevant for building curves in aftershot. They contain
- * comma separated versions of the curve information for all available curves (RGB, R, G, B).
+ * There are 8 attributes that describe the curves in aftershot. Each of them is a matrix with one
+ * row per curve (RGB, R, G, B) in the following form:
  *
+ *     <rows>,<columns>,<values of row 1>,<values of row 2>,...
+ *
+ * So `4,1,...` is a matrix with a single value per channel and `4,20,...` is a matrix with 20 values per channel.
  * When luminance values are used they are 16bit integers (0-65535)
  *
- * bopt:curves_m_cn: Number of points in the curve.
- *		- Is prefixed with `4,1` (Not sure what these values mean)
- *      - Has the number of points in the curve as integers following that (minimum 2 for black & white)
+ * bopt:curves_m_cn: Number of points in the curve (4 x 1)
+ *      - Minimum 2 for black & white
  *      - Example: `4,1,4,2,2,2`
  *          -> Has a curve with 2 additional points (black + white + 2 others) for RGB
  *
- * bopt:curves_m_cx: Point locations on X axis (input)
- * bopt:curves_m_cy: Point locations on Y axis (output)
- *      - Prefixed with `4,20` (Note sure what 4 means, 20 seems to be the number of points per channel [see more below])
- *      - Each channel has a list of 20 values (presumably the `20` in the prefix) describing up to 20 points in the curve.
+ * bopt:curves_m_cx: Point locations on X axis (input, 4 x 20)
+ * bopt:curves_m_cy: Point locations on Y axis (output, 4 x 20)
+ *      - Each channel has a list of 20 values describing up to 20 points in the curve.
  *        only the first `curves_m_cn` are read from that list for each channel. So if `curves_m_cn` is set to 4 for the
  *        RGB channel (as is the case in the example above), then the first 4 of 20 values for RGB will be read, the rest will
  *        be ignored.
@@ -36,10 +39,9 @@ import (
  * bopt:curves_m_olo: Output Black point for channels (black triangle left)
  * bopt:curves_m_ohi: Output White point for channels (white triangle left)
  * bopt:curves_m_ilo: Input Black point for channels (black triangle bottom)
- * bopt:curves_m_imid: Input Mid poitn for channels (gray triangle bottom) [gamma]
+ * bopt:curves_m_imid: Input Mid point for channels (gray triangle bottom) [gamma, 1 is neutral]
  * bopt:curves_m_ihi: Input White point for channels (white triangle bottom)
- * 		- Prefixed by `4,1` (Not sure what these values mean)
- *      - Followed by one value per channel
+ *      - All of them are 4 x 1 matrices
  *      - Note the following difference: You can drag the last point of the curve down (or the first up) to effectively change
  *        the black & white points. That however will only change the X and Y positions of the points. The black and white points
  *        in aftershot correspond to the black and white triangles on the left side.
@@ -47,62 +49,119 @@ import (
  *
  */
 
-type AfterShotToneCurvePoint struct {
-	In  int
-	Out int
+const AFTERSHOT_CURVE_CHANNELS = 4
+
+// A matrix as it is used in the curve attributes
+type afterShotMatrix struct {
+	Rows    int
+	Columns int
+	Values  []float64
 }
 
-type AfterShotToneCurveChannel struct {
-	Points []AfterShotToneCurvePoint
+func newAfterShotMatrix(rows int, columns int) afterShotMatrix {
+	return afterShotMatrix{Rows: rows, Columns: columns, Values: make([]float64, rows*columns)}
 }
 
-func (self *AfterShotToneCurveChannel) serializePoints(points []int, maxNumberOfPoints int) string {
-	serialized := make([]string, maxNumberOfPoints)
+func parseAfterShotMatrix(serialized string) (afterShotMatrix, error) {
+	parts := strings.Split(serialized, ",")
+	if len(parts) < 2 {
+		return afterShotMatrix{}, fmt.Errorf("'%s' is not a matrix", serialized)
+	}
 
-	// Fill with empty values
-	for index, _ := range serialized {
-		serialized[index] = "0"
+	rows, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil {
+		return afterShotMatrix{}, fmt.Errorf("invalid number of rows in '%s': %s", serialized, err)
+	}
+	columns, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+	if err != nil {
+		return afterShotMatrix{}, fmt.Errorf("invalid number of columns in '%s': %s", serialized, err)
+	}
+	if rows < 0 || columns < 0 || len(parts)-2 != rows*columns {
+		return afterShotMatrix{}, fmt.Errorf("matrix '%s' should have %dx%d values but has %d", serialized, rows, columns, len(parts)-2)
 	}
 
-	// Every valid curve must have at least 2 points.
-	if len(points) < 2 {
-		serialized[0] = "0"
-		serialized[1] = strconv.Itoa(AFTERSHOT_CURVE_MAX)
-	} else {
-		for index, value := range points {
-			serialized[index] = strconv.Itoa(value)
+	matrix := newAfterShotMatrix(rows, columns)
+	for index, part := range parts[2:] {
+		matrix.Values[index], err = strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return afterShotMatrix{}, fmt.Errorf("invalid value in '%s': %s", serialized, err)
 		}
 	}
 
-	return strings.Join(serialized, ",")
+	return matrix, nil
+}
+
+func (self afterShotMatrix) Get(row int, column int) float64 {
+	return self.Values[row*self.Columns+column]
 }
 
-func (self *AfterShotToneCurveChannel) SerializeNumberOfPoints() string {
-	if len(self.Points) < 2 {
-		return "2"
+func (self *afterShotMatrix) Set(row int, column int, value float64) {
+	self.Values[row*self.Columns+column] = value
+}
+
+func (self afterShotMatrix) Serialize() string {
+	serialized := []string{strconv.Itoa(self.Rows), strconv.Itoa(self.Columns)}
+	for _, value := range self.Values {
+		serialized = append(serialized, strconv.FormatFloat(value, 'f', -1, 64))
 	}
+	return strings.Join(serialized, ",")
+}
+
+// ---
 
-	return strconv.Itoa(len(self.Points))
+type AfterShotToneCurvePoint struct {
+	In  int
+	Out int
 }
 
-func (self *AfterShotToneCurveChannel) SerializePointsIn(maxNumberOfPoints int) string {
-	points := make([]int, len(self.Points))
+// A single curve including its levels. Levels are 16bit values, gamma is neutral at 1.
+type AfterShotToneCurveChannel struct {
+	Points []AfterShotToneCurvePoint
+
+	InputBlack  int
+	InputGamma  float64
+	InputWhite  int
+	OutputBlack int
+	OutputWhite int
+}
 
-	for index, point := range self.Points {
-		points[index] = point.In
+func newAfterShotToneCurveChannel(points []AfterShotToneCurvePoint) AfterShotToneCurveChannel {
+	return AfterShotToneCurveChannel{
+		Points:      points,
+		InputBlack:  0,
+		InputGamma:  1,
+		InputWhite:  AFTERSHOT_CURVE_MAX,
+		OutputBlack: 0,
+		OutputWhite: AFTERSHOT_CURVE_MAX,
 	}
-
-	return self.serializePoints(points, maxNumberOfPoints)
 }
 
-func (self *AfterShotToneCurveChannel) SerializePointsOut(maxNumberOfPoints int) string {
-	points := make([]int, len(self.Points))
+// Every valid curve must have at least 2 points, a curve without points is a straight line.
+func (self AfterShotToneCurveChannel) serializablePoints(maxNumberOfPoints int) []AfterShotToneCurvePoint {
+	points := self.Points
+	if len(points) < 2 {
+		points = []AfterShotToneCurvePoint{{In: 0, Out: 0}, {In: AFTERSHOT_CURVE_MAX, Out: AFTERSHOT_CURVE_MAX}}
+	}
 
-	for index, point := range self.Points {
-		points[index] = point.Out
+	if len(points) > maxNumberOfPoints {
+		log.Printf("[WARN] Aftershot curves can only have %d points, %d points of the curve will be ignored.", maxNumberOfPoints, len(points)-maxNumberOfPoints)
+		return points[:maxNumberOfPoints]
 	}
 
-	return self.serializePoints(points, maxNumberOfPoints)
+	return points
+}
+
+// A channel without points or whose points all lie on the diagonal, with neutral levels, does not
+// change the image. Lightroom presets often contain such curves (e.g. `0, 0, 255, 255`).
+func (self AfterShotToneCurveChannel) isIdentity() bool {
+	for _, point := range self.Points {
+		if point.In != point.Out {
+			return false
+		}
+	}
+
+	return self.InputBlack == 0 && self.InputGamma == 1 && self.InputWhite == AFTERSHOT_CURVE_MAX &&
+		self.OutputBlack == 0 && self.OutputWhite == AFTERSHOT_CURVE_MAX
 }
 
 // ---
@@ -112,52 +171,238 @@ type AfterShotCombinedToneCurve struct {
 	Red   AfterShotToneCurveChannel
 	Green AfterShotToneCurveChannel
 	Blue  AfterShotToneCurveChannel
+
+	// Number of point slots per channel
+	MaxNumberOfPoints int
+
+	// The attributes the curve was read from, nil for new curves
+	source *afterShotToneCurveSource
+}
+
+// Curves read from a preset remember the matrices and channels they were read from.
+// Channels that were not changed are written back exactly as they were read, including
+// values in unused point slots and channels aftershot would not accept from the converter.
+type afterShotToneCurveSource struct {
+	matrices map[string]afterShotMatrix
+	channels [AFTERSHOT_CURVE_CHANNELS]AfterShotToneCurveChannel
 }
 
-func (self *AfterShotCombinedToneCurve) SerializeNumberOfPoints() string {
-	return fmt.Sprintf(
-		"4,1,%s,%s,%s,%s",
-		self.Rgb.SerializeNumberOfPoints(),
-		self.Red.SerializeNumberOfPoints(),
-		self.Green.SerializeNumberOfPoints(),
-		self.Blue.SerializeNumberOfPoints(),
-	)
+func NewAfterShotCombinedToneCurve() AfterShotCombinedToneCurve {
+	return AfterShotCombinedToneCurve{
+		Rgb:               newAfterShotToneCurveChannel(nil),
+		Red:               newAfterShotToneCurveChannel(nil),
+		Green:             newAfterShotToneCurveChannel(nil),
+		Blue:              newAfterShotToneCurveChannel(nil),
+		MaxNumberOfPoints: AFTERSHOT_NUM_POINTS,
+	}
 }
 
-func (self *AfterShotCombinedToneCurve) SerializePointsIn(maxNumberOfPoints int) string {
-	return fmt.Sprintf(
-		"4,%d,%s,%s,%s,%s",
-		maxNumberOfPoints,
-		self.Rgb.SerializePointsIn(maxNumberOfPoints),
-		self.Red.SerializePointsIn(maxNumberOfPoints),
-		self.Green.SerializePointsIn(maxNumberOfPoints),
-		self.Blue.SerializePointsIn(maxNumberOfPoints),
-	)
+// The channels in the order of the matrix rows
+func (self *AfterShotCombinedToneCurve) channels() [AFTERSHOT_CURVE_CHANNELS]*AfterShotToneCurveChannel {
+	return [AFTERSHOT_CURVE_CHANNELS]*AfterShotToneCurveChannel{&self.Rgb, &self.Red, &self.Green, &self.Blue}
+}
+
+// Starts with the matrix that was read, if it has the expected number of columns
+func (self AfterShotCombinedToneCurve) matrix(name string, columns int) afterShotMatrix {
+	if self.source != nil {
+		matrix, exists := self.source.matrices[name]
+		if exists && matrix.Columns == columns {
+			matrix.Values = append([]float64{}, matrix.Values...)
+			return matrix
+		}
+	}
+	return newAfterShotMatrix(AFTERSHOT_CURVE_CHANNELS, columns)
 }
 
-func (self *AfterShotCombinedToneCurve) SerializePointsOut(maxNumberOfPoints int) string {
-	return fmt.Sprintf(
-		"4,%d,%s,%s,%s,%s",
-		maxNumberOfPoints,
-		self.Rgb.SerializePointsOut(maxNumberOfPoints),
-		self.Red.SerializePointsOut(maxNumberOfPoints),
-		self.Green.SerializePointsOut(maxNumberOfPoints),
-		self.Blue.SerializePointsOut(maxNumberOfPoints),
-	)
+// Matrices that were read with fewer rows grow when a channel in a missing row is written
+func (self *afterShotMatrix) setRow(row int, values []float64) {
+	for self.Rows <= row {
+		self.Values = append(self.Values, make([]float64, self.Columns)...)
+		self.Rows++
+	}
+	copy(self.Values[row*self.Columns:(row+1)*self.Columns], values)
 }
 
-func (self *AfterShotCombinedToneCurve) ToXmlAttributes() []xml.Attr {
-	maxList := fmt.Sprintf("4,1,%[1]d,%[1]d,%[1]d,%[1]d", AFTERSHOT_CURVE_MAX)
-	log.Print(maxList)
+func (self AfterShotCombinedToneCurve) ToXmlAttributes() []xml.Attr {
+	numberOfPoints := self.matrix("curves_m_cn", 1)
+	pointsIn := self.matrix("curves_m_cx", self.MaxNumberOfPoints)
+	pointsOut := self.matrix("curves_m_cy", self.MaxNumberOfPoints)
+	outputBlack := self.matrix("curves_m_olo", 1)
+	outputWhite := self.matrix("curves_m_ohi", 1)
+	inputBlack := self.matrix("curves_m_ilo", 1)
+	inputGamma := self.matrix("curves_m_imid", 1)
+	inputWhite := self.matrix("curves_m_ihi", 1)
+
+	changed := [AFTERSHOT_CURVE_CHANNELS]bool{}
+	lastChanged := -1
+	for row, channel := range self.channels() {
+		changed[row] = self.source == nil || !reflect.DeepEqual(*channel, self.source.channels[row])
+		if changed[row] {
+			lastChanged = row
+		}
+	}
+
+	for row, channel := range self.channels() {
+		points := channel.serializablePoints(self.MaxNumberOfPoints)
+		in := make([]float64, self.MaxNumberOfPoints)
+		out := make([]float64, self.MaxNumberOfPoints)
+		for column, point := range points {
+			in[column] = float64(point.In)
+			out[column] = float64(point.Out)
+		}
+
+		rows := []struct {
+			Matrix *afterShotMatrix
+			Values []float64
+		}{
+			{&numberOfPoints, []float64{float64(len(points))}},
+			{&pointsIn, in},
+			{&pointsOut, out},
+			{&outputBlack, []float64{float64(channel.OutputBlack)}},
+			{&outputWhite, []float64{float64(channel.OutputWhite)}},
+			{&inputBlack, []float64{float64(channel.InputBlack)}},
+			{&inputGamma, []float64{channel.InputGamma}},
+			{&inputWhite, []float64{float64(channel.InputWhite)}},
+		}
+		for _, matrixRow := range rows {
+			// Unchanged channels are only written to fill the gap up to a changed channel
+			// in matrices that were read with fewer rows
+			if changed[row] || (row >= matrixRow.Matrix.Rows && row < lastChanged) {
+				matrixRow.Matrix.setRow(row, matrixRow.Values)
+			}
+		}
+	}
 
 	return []xml.Attr{
-		{Name: xml.Name{Local: "bopt:curves_m_cn"}, Value: self.SerializeNumberOfPoints()},
-		{Name: xml.Name{Local: "bopt:curves_m_cx"}, Value: self.SerializePointsIn(AFTERSHOT_NUM_POINTS)},
-		{Name: xml.Name{Local: "bopt:curves_m_cy"}, Value: self.SerializePointsOut(AFTERSHOT_NUM_POINTS)},
-		{Name: xml.Name{Local: "bopt:curves_m_olo"}, Value: "4,1,0,0,0,0"},
-		{Name: xml.Name{Local: "bopt:curves_m_ohi"}, Value: maxList},
-		{Name: xml.Name{Local: "bopt:curves_m_ilo"}, Value: "4,1,0,0,0,0"},
-		{Name: xml.Name{Local: "bopt:curves_m_imid"}, Value: "4,1,1,1,1,1"},
-		{Name: xml.Name{Local: "bopt:curves_m_ihi"}, Value: maxList},
+		{Name: xml.Name{Local: "bopt:curves_m_cn"}, Value: numberOfPoints.Serialize()},
+		{Name: xml.Name{Local: "bopt:curves_m_cx"}, Value: pointsIn.Serialize()},
+		{Name: xml.Name{Local: "bopt:curves_m_cy"}, Value: pointsOut.Serialize()},
+		{Name: xml.Name{Local: "bopt:curves_m_olo"}, Value: outputBlack.Serialize()},
+		{Name: xml.Name{Local: "bopt:curves_m_ohi"}, Value: outputWhite.Serialize()},
+		{Name: xml.Name{Local: "bopt:curves_m_ilo"}, Value: inputBlack.Serialize()},
+		{Name: xml.Name{Local: "bopt:curves_m_imid"}, Value: inputGamma.Serialize()},
+		{Name: xml.Name{Local: "bopt:curves_m_ihi"}, Value: inputWhite.Serialize()},
+	}
+}
+
+// Reads the curves from the attributes of an aftershot preset or sidecar. Attributes that are
+// missing keep their neutral values, attributes not related to curves are skipped.
+// Matrices may have any number of rows, channels without a row keep their neutral values.
+// Writing the curve again returns the same attributes as long as the channels are not changed.
+func NewAfterShotCombinedToneCurveFromXmlAttributes(attributes []xml.Attr) (AfterShotCombinedToneCurve, error) {
+	curve := NewAfterShotCombinedToneCurve()
+
+	matrices := map[string]afterShotMatrix{}
+	for _, attribute := range attributes {
+		name := attribute.Name.Local[strings.LastIndex(attribute.Name.Local, ":")+1:]
+		if !strings.HasPrefix(name, "curves_m_") {
+			continue
+		}
+
+		matrix, err := parseAfterShotMatrix(attribute.Value)
+		if err != nil {
+			return curve, fmt.Errorf("%s: %s", name, err)
+		}
+		if name != "curves_m_cx" && name != "curves_m_cy" && matrix.Columns != 1 {
+			return curve, fmt.Errorf("%s: expected a single value per channel but found %d", name, matrix.Columns)
+		}
+		matrices[name] = matrix
+	}
+
+	levels := []struct {
+		Name  string
+		Value func(channel *AfterShotToneCurveChannel, value float64) error
+	}{
+		{"curves_m_olo", func(channel *AfterShotToneCurveChannel, value float64) (err error) {
+			channel.OutputBlack, err = curveInteger(value)
+			return err
+		}},
+		{"curves_m_ohi", func(channel *AfterShotToneCurveChannel, value float64) (err error) {
+			channel.OutputWhite, err = curveInteger(value)
+			return err
+		}},
+		{"curves_m_ilo", func(channel *AfterShotToneCurveChannel, value float64) (err error) {
+			channel.InputBlack, err = curveInteger(value)
+			return err
+		}},
+		{"curves_m_imid", func(channel *AfterShotToneCurveChannel, value float64) error {
+			channel.InputGamma = value
+			return nil
+		}},
+		{"curves_m_ihi", func(channel *AfterShotToneCurveChannel, value float64) (err error) {
+			channel.InputWhite, err = curveInteger(value)
+			return err
+		}},
+	}
+	for _, level := range levels {
+		matrix, exists := matrices[level.Name]
+		if !exists {
+			continue
+		}
+		for row, channel := range curve.channels() {
+			if row >= matrix.Rows {
+				break
+			}
+			err := level.Value(channel, matrix.Get(row, 0))
+			if err != nil {
+				return curve, fmt.Errorf("%s: %s", level.Name, err)
+			}
+		}
+	}
+
+	numberOfPoints, hasNumberOfPoints := matrices["curves_m_cn"]
+	pointsIn, hasPointsIn := matrices["curves_m_cx"]
+	pointsOut, hasPointsOut := matrices["curves_m_cy"]
+	if hasNumberOfPoints || hasPointsIn || hasPointsOut {
+		if !hasNumberOfPoints || !hasPointsIn || !hasPointsOut {
+			return curve, fmt.Errorf("curves_m_cn, curves_m_cx and curves_m_cy must be used together")
+		}
+		if pointsIn.Columns != pointsOut.Columns {
+			return curve, fmt.Errorf("curves_m_cx has %d points per channel but curves_m_cy has %d", pointsIn.Columns, pointsOut.Columns)
+		}
+		curve.MaxNumberOfPoints = pointsIn.Columns
+	}
+
+	for row, channel := range curve.channels() {
+		if !hasNumberOfPoints || row >= numberOfPoints.Rows {
+			break
+		}
+
+		count, err := curveInteger(numberOfPoints.Get(row, 0))
+		if err != nil {
+			return curve, fmt.Errorf("curves_m_cn: %s", err)
+		}
+		if count < 0 || count > curve.MaxNumberOfPoints || row >= pointsIn.Rows || row >= pointsOut.Rows {
+			return curve, fmt.Errorf("curves_m_cn: channel %d has %d points, but there are only %d", row, count, curve.MaxNumberOfPoints)
+		}
+
+		channel.Points = make([]AfterShotToneCurvePoint, count)
+		for column := range channel.Points {
+			channel.Points[column].In, err = curveInteger(pointsIn.Get(row, column))
+			if err != nil {
+				return curve, fmt.Errorf("curves_m_cx: %s", err)
+			}
+			channel.Points[column].Out, err = curveInteger(pointsOut.Get(row, column))
+			if err != nil {
+				return curve, fmt.Errorf("curves_m_cy: %s", err)
+			}
+		}
+	}
+
+	curve.source = &afterShotToneCurveSource{matrices: matrices}
+	for row, channel := range curve.channels() {
+		curve.source.channels[row] = *channel
+		if channel.Points != nil {
+			curve.source.channels[row].Points = append([]AfterShotToneCurvePoint{}, channel.Points...)
+		}
+	}
+
+	return curve, nil
+}
+
+func curveInteger(value float64) (int, error) {
+	if value != math.Trunc(value) {
+		return 0, fmt.Errorf("%s is not an integer", strconv.FormatFloat(value, 'f', -1, 64))
 	}
+	return int(value), nil
 }
@@ -0,0 +1,157 @@
+package lib
+
+import (
+	"encoding/xml"
+	"math/rand"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/quick"
+)
+
+// Curve attributes of any shape the writer can produce, with garbage in the unused point slots
+type afterShotCurveAttributes []xml.Attr
+
+func (afterShotCurveAttributes) Generate(random *rand.Rand, size int) reflect.Value {
+	rows := random.Intn(AFTERSHOT_CURVE_CHANNELS + 3)
+	columns := random.Intn(AFTERSHOT_NUM_POINTS + 5)
+
+	matrix := func(columns int, value func(row int, column int) string) string {
+		values := []string{strconv.Itoa(rows), strconv.Itoa(columns)}
+		for row := 0; row < rows; row++ {
+			for column := 0; column < columns; column++ {
+				values = append(values, value(row, column))
+			}
+		}
+		return strings.Join(values, ",")
+	}
+	integer := func(int, int) string {
+		return strconv.Itoa(random.Intn(AFTERSHOT_CURVE_MAX + 1))
+	}
+
+	counts := make([]int, rows)
+	for row := range counts {
+		counts[row] = random.Intn(columns + 1)
+	}
+	point := func(row int, column int) string {
+		if column < counts[row] {
+			return integer(row, column)
+		}
+		// Unused slots are not read and may contain anything
+		return strconv.FormatFloat(random.NormFloat64()*1000, 'f', -1, 64)
+	}
+
+	attributes := afterShotCurveAttributes{
+		{Name: xml.Name{Local: "bopt:curves_m_cn"}, Value: matrix(1, func(row int, _ int) string { return strconv.Itoa(counts[row]) })},
+		{Name: xml.Name{Local: "bopt:curves_m_cx"}, Value: matrix(columns, point)},
+		{Name: xml.Name{Local: "bopt:curves_m_cy"}, Value: matrix(columns, point)},
+		{Name: xml.Name{Local: "bopt:curves_m_olo"}, Value: matrix(1, integer)},
+		{Name: xml.Name{Local: "bopt:curves_m_ohi"}, Value: matrix(1, integer)},
+		{Name: xml.Name{Local: "bopt:curves_m_ilo"}, Value: matrix(1, integer)},
+		{Name: xml.Name{Local: "bopt:curves_m_imid"}, Value: matrix(1, func(int, int) string {
+			return strconv.FormatFloat(random.Float64()*3, 'f', -1, 64)
+		})},
+		{Name: xml.Name{Local: "bopt:curves_m_ihi"}, Value: matrix(1, integer)},
+	}
+	return reflect.ValueOf(attributes)
+}
+
+func TestAfterShotCurveAttributesRoundTrip(t *testing.T) {
+	roundTrip := func(attributes afterShotCurveAttributes) bool {
+		curve, err := NewAfterShotCombinedToneCurveFromXmlAttributes(attributes)
+		if err != nil {
+			t.Log(err)
+			return false
+		}
+		return reflect.DeepEqual(curve.ToXmlAttributes(), []xml.Attr(attributes))
+	}
+
+	err := quick.Check(roundTrip, nil)
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestAfterShotCurveRoundTripOfWrittenCurves(t *testing.T) {
+	roundTrip := func(points [AFTERSHOT_CURVE_CHANNELS][]uint16, gamma [AFTERSHOT_CURVE_CHANNELS]uint8) bool {
+		curve := NewAfterShotCombinedToneCurve()
+		for row, channel := range curve.channels() {
+			for index := 0; index+1 < len(points[row]) && len(channel.Points) < curve.MaxNumberOfPoints; index += 2 {
+				channel.Points = append(channel.Points, AfterShotToneCurvePoint{In: int(points[row][index]), Out: int(points[row][index+1])})
+			}
+			channel.InputGamma = float64(gamma[row]) / 100
+		}
+
+		written := curve.ToXmlAttributes()
+		read, err := NewAfterShotCombinedToneCurveFromXmlAttributes(written)
+		if err != nil {
+			t.Log(err)
+			return false
+		}
+		return reflect.DeepEqual(read.ToXmlAttributes(), written)
+	}
+
+	err := quick.Check(roundTrip, nil)
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestAfterShotCurveWritesChangedChannels(t *testing.T) {
+	attributes := []xml.Attr{
+		{Name: xml.Name{Local: "bopt:curves_m_cn"}, Value: "2,1,3,1"},
+		{Name: xml.Name{Local: "bopt:curves_m_cx"}, Value: "2,4,0,100,65535,7,5,-1,2.5,9"},
+		{Name: xml.Name{Local: "bopt:curves_m_cy"}, Value: "2,4,0,200,65535,7,5,-1,2.5,9"},
+	}
+
+	curve, err := NewAfterShotCombinedToneCurveFromXmlAttributes(attributes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(curve.Red.Points) != 1 || curve.Red.Points[0] != (AfterShotToneCurvePoint{In: 5, Out: 5}) {
+		t.Errorf("red channel = %+v", curve.Red.Points)
+	}
+	if curve.Green.Points != nil {
+		t.Errorf("green channel without a row should be neutral, got %+v", curve.Green.Points)
+	}
+
+	// The neutral green channel fills the gap to the blue one
+	curve.Rgb.Points = []AfterShotToneCurvePoint{{0, 0}, {65535, 60000}}
+	curve.Blue.Points = []AfterShotToneCurvePoint{{0, 1000}, {65535, 65535}}
+
+	expected := map[string]string{
+		"bopt:curves_m_cn": "4,1,2,1,2,2",
+		"bopt:curves_m_cx": "4,4,0,65535,0,0,5,-1,2.5,9,0,65535,0,0,0,65535,0,0",
+		"bopt:curves_m_cy": "4,4,0,60000,0,0,5,-1,2.5,9,0,65535,0,0,1000,65535,0,0",
+	}
+	for _, attribute := range curve.ToXmlAttributes() {
+		value, isExpected := expected[attribute.Name.Local]
+		if isExpected && attribute.Value != value {
+			t.Errorf("%s = '%s', expected '%s'", attribute.Name.Local, attribute.Value, value)
+		}
+	}
+}
+
+func TestAfterShotCurveRejectsInvalidAttributes(t *testing.T) {
+	tests := [][]xml.Attr{
+		{{Name: xml.Name{Local: "bopt:curves_m_cn"}, Value: "4,1,2,2,2"}},
+		{{Name: xml.Name{Local: "bopt:curves_m_olo"}, Value: "1,2,0,0"}},
+		{{Name: xml.Name{Local: "bopt:curves_m_olo"}, Value: "1,1,0.5"}},
+		{
+			{Name: xml.Name{Local: "bopt:curves_m_cn"}, Value: "1,1,3"},
+			{Name: xml.Name{Local: "bopt:curves_m_cx"}, Value: "1,2,0,65535"},
+			{Name: xml.Name{Local: "bopt:curves_m_cy"}, Value: "1,2,0,65535"},
+		},
+		{
+			{Name: xml.Name{Local: "bopt:curves_m_cn"}, Value: "1,1,2"},
+			{Name: xml.Name{Local: "bopt:curves_m_cx"}, Value: "1,2,0,65535"},
+		},
+	}
+
+	for _, attributes := range tests {
+		if _, err := NewAfterShotCombinedToneCurveFromXmlAttributes(attributes); err == nil {
+			t.Errorf("expected an error for %v", attributes)
+		}
+	}
+}
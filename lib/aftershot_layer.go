@@ -0,0 +1,164 @@
+package lib
+
+import (
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const AFTERSHOT_REGION_CIRCLE = "circle"
+const AFTERSHOT_REGION_POLYGON = "polygon"
+
+// A region of a layer. Coordinates are relative to the image (0 - 1), the feather is relative
+// to the image width. Regions of the same layer are added together.
+type AfterShotRegion struct {
+	Kind string
+
+	// Circles (ellipses) are described by their center, radii and rotation in degrees
+	CenterX float64
+	CenterY float64
+	RadiusX float64
+	RadiusY float64
+	Angle   float64
+
+	// Corners of polygons
+	Points [][2]float64
+
+	Feather  float64
+	Inverted bool
+}
+
+func (self AfterShotRegion) toXmlAttributes() []xml.Attr {
+	attributes := []xml.Attr{
+		{Name: xml.Name{Local: "breg:type"}, Value: self.Kind},
+		{Name: xml.Name{Local: "breg:feather"}, Value: formatRegionNumber(self.Feather)},
+		{Name: xml.Name{Local: "breg:inverted"}, Value: formatFlag(self.Inverted)},
+	}
+
+	switch self.Kind {
+	case AFTERSHOT_REGION_CIRCLE:
+		attributes = append(
+			attributes,
+			xml.Attr{Name: xml.Name{Local: "breg:cx"}, Value: formatRegionNumber(self.CenterX)},
+			xml.Attr{Name: xml.Name{Local: "breg:cy"}, Value: formatRegionNumber(self.CenterY)},
+			xml.Attr{Name: xml.Name{Local: "breg:rx"}, Value: formatRegionNumber(self.RadiusX)},
+			xml.Attr{Name: xml.Name{Local: "breg:ry"}, Value: formatRegionNumber(self.RadiusY)},
+			xml.Attr{Name: xml.Name{Local: "breg:angle"}, Value: formatRegionNumber(self.Angle)},
+		)
+	case AFTERSHOT_REGION_POLYGON:
+		points := make([]string, len(self.Points))
+		for index, point := range self.Points {
+			points[index] = formatRegionNumber(point[0]) + "," + formatRegionNumber(point[1])
+		}
+		attributes = append(attributes, xml.Attr{Name: xml.Name{Local: "breg:points"}, Value: strings.Join(points, " ")})
+	}
+
+	return attributes
+}
+
+func formatRegionNumber(value float64) string {
+	return fmt.Sprintf("%.6f", value)
+}
+
+func formatFlag(value bool) string {
+	if value {
+		return "True"
+	}
+	return "False"
+}
+
+// ---
+
+// Options that can be used in additional layers
+type AfterShotLayerSettings struct {
+	Exposure          AfterShotOption
+	Contrast          AfterShotOption
+	HighlightRecovery AfterShotOption
+	FillLight         AfterShotOption
+	Saturation        AfterShotOption
+}
+
+func newAfterShotLayerSettings() AfterShotLayerSettings {
+	return AfterShotLayerSettings{
+		Exposure:          newAfterShotFloat("exposureval", -4, 4, 0),
+		Contrast:          newAfterShotInt("scont", -100, 100, 0),
+		HighlightRecovery: newAfterShotInt("highlightrecval", 0, 100, 0),
+		FillLight:         newAfterShotFloat("fillamount", 0, 1, 0),
+		Saturation:        newAfterShotInt("sat", -100, 100, 0),
+	}
+}
+
+func (self *AfterShotLayerSettings) Options() []*AfterShotOption {
+	return []*AfterShotOption{
+		&self.Exposure,
+		&self.Contrast,
+		&self.HighlightRecovery,
+		&self.FillLight,
+		&self.Saturation,
+	}
+}
+
+// ---
+
+// An additional adjustment layer that only applies to its regions
+type AfterShotLayer struct {
+	Name     string
+	Opacity  float64
+	Settings AfterShotLayerSettings
+	Regions  []AfterShotRegion
+}
+
+func NewAfterShotLayer(name string) AfterShotLayer {
+	return AfterShotLayer{
+		Name:     name,
+		Opacity:  1,
+		Settings: newAfterShotLayerSettings(),
+	}
+}
+
+func (self *AfterShotLayer) toXmlTokens(id int) []xml.Token {
+	options := []xml.Attr{}
+	for _, option := range self.Settings.Options() {
+		if option.IsSet {
+			options = append(options, option.ToXmlAttribute())
+		}
+	}
+	sort.Slice(options, func(i, j int) bool {
+		return options[i].Name.Local < options[j].Name.Local
+	})
+
+	tokens := []xml.Token{
+		xml.StartElement{Name: xml.Name{Local: "rdf:li"}},
+		xml.StartElement{
+			Name: xml.Name{Local: "rdf:Description"},
+			Attr: []xml.Attr{
+				{Name: xml.Name{Local: "blay:layerId"}, Value: fmt.Sprint(id)},
+				{Name: xml.Name{Local: "blay:layerPos"}, Value: fmt.Sprint(id)},
+				{Name: xml.Name{Local: "blay:name"}, Value: self.Name},
+				{Name: xml.Name{Local: "blay:enabled"}, Value: "True"},
+				{Name: xml.Name{Local: "blay:opacity"}, Value: formatRegionNumber(self.Opacity)},
+			},
+		},
+		xml.StartElement{Name: xml.Name{Local: "blay:options"}, Attr: options},
+		xml.EndElement{Name: xml.Name{Local: "blay:options"}},
+		xml.StartElement{Name: xml.Name{Local: "blay:regions"}},
+		xml.StartElement{Name: xml.Name{Local: "rdf:Seq"}},
+	}
+
+	for _, region := range self.Regions {
+		tokens = append(
+			tokens,
+			xml.StartElement{Name: xml.Name{Local: "rdf:li"}, Attr: region.toXmlAttributes()},
+			xml.EndElement{Name: xml.Name{Local: "rdf:li"}},
+		)
+	}
+
+	return append(
+		tokens,
+		xml.EndElement{Name: xml.Name{Local: "rdf:Seq"}},
+		xml.EndElement{Name: xml.Name{Local: "blay:regions"}},
+		xml.EndElement{Name: xml.Name{Local: "rdf:Description"}},
+		xml.EndElement{Name: xml.Name{Local: "rdf:li"}},
+	)
+}
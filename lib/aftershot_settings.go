@@ -0,0 +1,489 @@
+package lib
+
+import (
+	"encoding/xml"
+	"log"
+	"math"
+	"sort"
+	"strconv"
+)
+
+const AFTERSHOT_OPTION_INT = "int"
+const AFTERSHOT_OPTION_FLOAT = "float"
+const AFTERSHOT_OPTION_BOOL = "bool"
+
+// A single aftershot option (`bopt:...`) with the format and bounds aftershot expects.
+// Only options that have been set are written to the preset.
+type AfterShotOption struct {
+	Name    string
+	Kind    string
+	Min     float64
+	Max     float64
+	Neutral float64
+	Value   float64
+	IsSet   bool
+}
+
+func newAfterShotInt(name string, min float64, max float64, neutral float64) AfterShotOption {
+	return AfterShotOption{Name: name, Kind: AFTERSHOT_OPTION_INT, Min: min, Max: max, Neutral: neutral}
+}
+
+func newAfterShotFloat(name string, min float64, max float64, neutral float64) AfterShotOption {
+	return AfterShotOption{Name: name, Kind: AFTERSHOT_OPTION_FLOAT, Min: min, Max: max, Neutral: neutral}
+}
+
+func newAfterShotBool(name string) AfterShotOption {
+	return AfterShotOption{Name: name, Kind: AFTERSHOT_OPTION_BOOL, Min: 0, Max: 1, Neutral: 0}
+}
+
+// Sets the value, values outside of the bounds of the option are clamped.
+func (self *AfterShotOption) Set(value float64) {
+	if value < self.Min || value > self.Max {
+		clamped := math.Max(self.Min, math.Min(self.Max, value))
+		log.Printf(
+			"[WARN] Value %s for option '%s' is out of range [%s, %s] and will be clamped to %s",
+			formatNumber(value),
+			self.Name,
+			formatNumber(self.Min),
+			formatNumber(self.Max),
+			formatNumber(clamped),
+		)
+		value = clamped
+	}
+
+	self.Value = value
+	self.IsSet = true
+}
+
+func (self *AfterShotOption) SetBool(value bool) {
+	if value {
+		self.Set(1)
+	} else {
+		self.Set(0)
+	}
+}
+
+func (self *AfterShotOption) Reset() {
+	self.Value = self.Neutral
+	self.IsSet = true
+}
+
+func (self *AfterShotOption) Unset() {
+	self.Value = 0
+	self.IsSet = false
+}
+
+func (self AfterShotOption) IsNeutral() bool {
+	return self.Serialize() == self.serializeValue(self.Neutral)
+}
+
+func (self AfterShotOption) Serialize() string {
+	return self.serializeValue(self.Value)
+}
+
+func (self AfterShotOption) serializeValue(value float64) string {
+	switch self.Kind {
+	case AFTERSHOT_OPTION_BOOL:
+		if value != 0 {
+			return "true"
+		}
+		return "false"
+	case AFTERSHOT_OPTION_INT:
+		return strconv.Itoa(int(math.Round(value)))
+	}
+
+	// Floats are limited to 6 decimals, further precision is not used by aftershot
+	return formatNumber(math.Round(value*1e6) / 1e6)
+}
+
+func (self AfterShotOption) ToXmlAttribute() xml.Attr {
+	return xml.Attr{
+		Name:  xml.Name{Local: "bopt:" + self.Name},
+		Value: self.Serialize(),
+	}
+}
+
+// ---
+
+type AfterShotBasic struct {
+	Exposure          AfterShotOption
+	Contrast          AfterShotOption
+	HighlightRecovery AfterShotOption
+	FillLight         AfterShotOption
+	Vibrance          AfterShotOption
+	Saturation        AfterShotOption
+	Sharpen           AfterShotOption
+}
+
+func newAfterShotBasic() AfterShotBasic {
+	return AfterShotBasic{
+		Exposure:          newAfterShotFloat("exposureval", -4, 4, 0),
+		Contrast:          newAfterShotInt("scont", -100, 100, 0),
+		HighlightRecovery: newAfterShotInt("highlightrecval", 0, 100, 0),
+		FillLight:         newAfterShotFloat("fillamount", 0, 1, 0),
+		Vibrance:          newAfterShotInt("vibe", -100, 100, 0),
+		Saturation:        newAfterShotInt("sat", -100, 100, 0),
+		Sharpen:           newAfterShotInt("newsharpen", 0, 200, 100),
+	}
+}
+
+func (self *AfterShotBasic) options() []*AfterShotOption {
+	return []*AfterShotOption{
+		&self.Exposure,
+		&self.Contrast,
+		&self.HighlightRecovery,
+		&self.FillLight,
+		&self.Vibrance,
+		&self.Saturation,
+		&self.Sharpen,
+	}
+}
+
+// ---
+
+type AfterShotCurves struct {
+	Enabled   AfterShotOption
+	ToneCurve AfterShotCombinedToneCurve
+}
+
+func newAfterShotCurves() AfterShotCurves {
+	return AfterShotCurves{
+		Enabled:   newAfterShotBool("curveson"),
+		ToneCurve: NewAfterShotCombinedToneCurve(),
+	}
+}
+
+func (self *AfterShotCurves) options() []*AfterShotOption {
+	return []*AfterShotOption{&self.Enabled}
+}
+
+func (self *AfterShotCurves) xmlAttributes() []xml.Attr {
+	return self.ToneCurve.ToXmlAttributes()
+}
+
+// ---
+
+const AFTERSHOT_EQUALIZER_NUM_BANDS = 7
+
+// Bands of the color equalizer, in order of their hue
+var AFTERSHOT_EQUALIZER_BANDS = [AFTERSHOT_EQUALIZER_NUM_BANDS]string{"red", "orange", "yellow", "green", "cyan", "blue", "magenta"}
+
+// Center hue of the bands in degrees, in the same order as AFTERSHOT_EQUALIZER_BANDS
+var AFTERSHOT_EQUALIZER_BAND_HUES = [AFTERSHOT_EQUALIZER_NUM_BANDS]float64{0, 30, 60, 120, 180, 240, 300}
+
+// Hue shift in degrees of a band at a hue value of 100
+const AFTERSHOT_EQUALIZER_HUE_DEGREES = 45
+
+// Options of the bands are in the same order as AFTERSHOT_EQUALIZER_BANDS
+type AfterShotEqualizer struct {
+	Enabled    AfterShotOption
+	Hue        [AFTERSHOT_EQUALIZER_NUM_BANDS]AfterShotOption
+	Saturation [AFTERSHOT_EQUALIZER_NUM_BANDS]AfterShotOption
+	Luminance  [AFTERSHOT_EQUALIZER_NUM_BANDS]AfterShotOption
+}
+
+func newAfterShotEqualizer() AfterShotEqualizer {
+	equalizer := AfterShotEqualizer{
+		Enabled: newAfterShotBool("Equalizer_kb.kbs_enabled"),
+	}
+
+	for index, band := range AFTERSHOT_EQUALIZER_BANDS {
+		equalizer.Hue[index] = newAfterShotFloat("Equalizer_kb.kbs_"+band+"hue", -100, 100, 0)
+		equalizer.Saturation[index] = newAfterShotInt("Equalizer_kb.kbs_"+band+"sat", -100, 100, 0)
+		equalizer.Luminance[index] = newAfterShotInt("Equalizer_kb.kbs_"+band+"lum", -100, 100, 0)
+	}
+
+	return equalizer
+}
+
+func (self *AfterShotEqualizer) options() []*AfterShotOption {
+	options := []*AfterShotOption{&self.Enabled}
+	for index := range AFTERSHOT_EQUALIZER_BANDS {
+		options = append(options, &self.Hue[index], &self.Saturation[index], &self.Luminance[index])
+	}
+	return options
+}
+
+// ---
+
+type AfterShotLocalContrast struct {
+	Enabled  AfterShotOption
+	Strength AfterShotOption
+	Radius   AfterShotOption
+}
+
+func newAfterShotLocalContrast() AfterShotLocalContrast {
+	return AfterShotLocalContrast{
+		Enabled:  newAfterShotBool("lc_enabled"),
+		Strength: newAfterShotInt("lc_strength", 0, 100, 0),
+		Radius:   newAfterShotInt("lc_radius", 1, 200, 50),
+	}
+}
+
+func (self *AfterShotLocalContrast) options() []*AfterShotOption {
+	return []*AfterShotOption{&self.Enabled, &self.Strength, &self.Radius}
+}
+
+// ---
+
+type AfterShotWaveletSharpen struct {
+	UsmEnabled AfterShotOption
+	UsmClarity AfterShotOption
+	UsmRadius  AfterShotOption
+	UsmAmount  AfterShotOption
+}
+
+func newAfterShotWaveletSharpen() AfterShotWaveletSharpen {
+	return AfterShotWaveletSharpen{
+		UsmEnabled: newAfterShotBool("WaveletSharpen2.bSphWaveletUsmon"),
+		UsmClarity: newAfterShotBool("WaveletSharpen2.bSphWaveletUsmClarity"),
+		UsmRadius:  newAfterShotFloat("WaveletSharpen2.bSphWaveletUsmRadius", 0, 100, 0),
+		UsmAmount:  newAfterShotInt("WaveletSharpen2.bSphWaveletUsmAmount", 0, 100, 0),
+	}
+}
+
+func (self *AfterShotWaveletSharpen) options() []*AfterShotOption {
+	return []*AfterShotOption{&self.UsmEnabled, &self.UsmClarity, &self.UsmRadius, &self.UsmAmount}
+}
+
+// ---
+
+type AfterShotNoise struct {
+	Enabled           AfterShotOption
+	LuminanceStrength AfterShotOption
+	LuminanceDetail   AfterShotOption
+	LuminanceContrast AfterShotOption
+	ChromaStrength    AfterShotOption
+	ChromaDetail      AfterShotOption
+}
+
+func newAfterShotNoise() AfterShotNoise {
+	return AfterShotNoise{
+		Enabled:           newAfterShotBool("nn_enabled"),
+		LuminanceStrength: newAfterShotInt("nn_lumastrength", 0, 100, 0),
+		LuminanceDetail:   newAfterShotInt("nn_lumadetail", 0, 100, 50),
+		LuminanceContrast: newAfterShotInt("nn_lumacontrast", 0, 100, 0),
+		ChromaStrength:    newAfterShotInt("nn_chromastrength", 0, 100, 0),
+		ChromaDetail:      newAfterShotInt("nn_chromadetail", 0, 100, 50),
+	}
+}
+
+func (self *AfterShotNoise) options() []*AfterShotOption {
+	return []*AfterShotOption{
+		&self.Enabled,
+		&self.LuminanceStrength,
+		&self.LuminanceDetail,
+		&self.LuminanceContrast,
+		&self.ChromaStrength,
+		&self.ChromaDetail,
+	}
+}
+
+// ---
+
+// Vignette that is applied after cropping
+type AfterShotVignette struct {
+	Enabled             AfterShotOption
+	Amount              AfterShotOption
+	Radius              AfterShotOption
+	Feather             AfterShotOption
+	Roundness           AfterShotOption
+	HighlightProtection AfterShotOption
+}
+
+func newAfterShotVignette() AfterShotVignette {
+	return AfterShotVignette{
+		Enabled:             newAfterShotBool("vig_enabled"),
+		Amount:              newAfterShotInt("vig_amount", -100, 100, 0),
+		Radius:              newAfterShotInt("vig_radius", 0, 100, 50),
+		Feather:             newAfterShotInt("vig_feather", 0, 100, 50),
+		Roundness:           newAfterShotInt("vig_roundness", -100, 100, 0),
+		HighlightProtection: newAfterShotInt("vig_highlights", 0, 100, 0),
+	}
+}
+
+func (self *AfterShotVignette) options() []*AfterShotOption {
+	return []*AfterShotOption{
+		&self.Enabled,
+		&self.Amount,
+		&self.Radius,
+		&self.Feather,
+		&self.Roundness,
+		&self.HighlightProtection,
+	}
+}
+
+// ---
+
+// Lens correction
+type AfterShotLens struct {
+	Enabled             AfterShotOption
+	VignetteAmount      AfterShotOption
+	VignetteMidpoint    AfterShotOption
+	Profile             AfterShotOption
+	Distortion          AfterShotOption
+	ChromaticAberration AfterShotOption
+	Defringe            AfterShotOption
+}
+
+func newAfterShotLens() AfterShotLens {
+	return AfterShotLens{
+		Enabled:             newAfterShotBool("lens_enabled"),
+		VignetteAmount:      newAfterShotInt("lens_vigamount", -100, 100, 0),
+		VignetteMidpoint:    newAfterShotInt("lens_vigmidpoint", 0, 100, 50),
+		Profile:             newAfterShotBool("lens_profile"),
+		Distortion:          newAfterShotInt("lens_distortion", -100, 100, 0),
+		ChromaticAberration: newAfterShotBool("lens_autoca"),
+		Defringe:            newAfterShotInt("lens_defringe", 0, 100, 0),
+	}
+}
+
+func (self *AfterShotLens) options() []*AfterShotOption {
+	return []*AfterShotOption{
+		&self.Enabled,
+		&self.VignetteAmount,
+		&self.VignetteMidpoint,
+		&self.Profile,
+		&self.Distortion,
+		&self.ChromaticAberration,
+		&self.Defringe,
+	}
+}
+
+// ---
+
+// Perspective correction, rotation and scaling
+type AfterShotTransform struct {
+	Enabled    AfterShotOption
+	Vertical   AfterShotOption
+	Horizontal AfterShotOption
+	Rotate     AfterShotOption
+	Scale      AfterShotOption
+	Aspect     AfterShotOption
+}
+
+func newAfterShotTransform() AfterShotTransform {
+	return AfterShotTransform{
+		Enabled:    newAfterShotBool("persp_enabled"),
+		Vertical:   newAfterShotFloat("persp_vertical", -100, 100, 0),
+		Horizontal: newAfterShotFloat("persp_horizontal", -100, 100, 0),
+		Rotate:     newAfterShotFloat("persp_rotate", -45, 45, 0),
+		Scale:      newAfterShotInt("persp_scale", 50, 150, 100),
+		Aspect:     newAfterShotInt("persp_aspect", -100, 100, 0),
+	}
+}
+
+func (self *AfterShotTransform) options() []*AfterShotOption {
+	return []*AfterShotOption{
+		&self.Enabled,
+		&self.Vertical,
+		&self.Horizontal,
+		&self.Rotate,
+		&self.Scale,
+		&self.Aspect,
+	}
+}
+
+// ---
+
+// All settings of an aftershot preset, grouped by the module they belong to
+type AfterShotSettings struct {
+	Basic          AfterShotBasic
+	Curves         AfterShotCurves
+	Equalizer      AfterShotEqualizer
+	LocalContrast  AfterShotLocalContrast
+	WaveletSharpen AfterShotWaveletSharpen
+	Noise          AfterShotNoise
+	Vignette       AfterShotVignette
+	Lens           AfterShotLens
+	Transform      AfterShotTransform
+}
+
+func NewAfterShotSettings() AfterShotSettings {
+	return AfterShotSettings{
+		Basic:          newAfterShotBasic(),
+		Curves:         newAfterShotCurves(),
+		Equalizer:      newAfterShotEqualizer(),
+		LocalContrast:  newAfterShotLocalContrast(),
+		WaveletSharpen: newAfterShotWaveletSharpen(),
+		Noise:          newAfterShotNoise(),
+		Vignette:       newAfterShotVignette(),
+		Lens:           newAfterShotLens(),
+		Transform:      newAfterShotTransform(),
+	}
+}
+
+// A module of aftershot, e.g. the color equalizer
+type afterShotModule interface {
+	options() []*AfterShotOption
+}
+
+// Modules that write attributes besides their options, e.g. the tone curve
+type afterShotModuleWithAttributes interface {
+	afterShotModule
+	xmlAttributes() []xml.Attr
+}
+
+// Returns the modules in the order they are written to the preset
+func (self *AfterShotSettings) modules() []afterShotModule {
+	return []afterShotModule{
+		&self.Basic,
+		&self.Curves,
+		&self.Equalizer,
+		&self.LocalContrast,
+		&self.WaveletSharpen,
+		&self.Noise,
+		&self.Vignette,
+		&self.Lens,
+		&self.Transform,
+	}
+}
+
+// Returns all options, grouped by module
+func (self *AfterShotSettings) Options() []*AfterShotOption {
+	options := []*AfterShotOption{}
+	for _, module := range self.modules() {
+		options = append(options, module.options()...)
+	}
+	return options
+}
+
+// Returns the option with the given name (without the `bopt:` prefix) or nil if it does not exist.
+func (self *AfterShotSettings) Option(name string) *AfterShotOption {
+	for _, option := range self.Options() {
+		if option.Name == name {
+			return option
+		}
+	}
+	return nil
+}
+
+// Sets every option to its neutral value
+func (self *AfterShotSettings) Reset() {
+	for _, option := range self.Options() {
+		option.Reset()
+	}
+}
+
+// Returns the attributes of all options that are set, grouped by module. Attributes are sorted
+// within their module in order to produce the same output every time.
+func (self *AfterShotSettings) ToXmlAttributes() []xml.Attr {
+	attributes := []xml.Attr{}
+	for _, module := range self.modules() {
+		moduleAttributes := []xml.Attr{}
+		for _, option := range module.options() {
+			if option.IsSet {
+				moduleAttributes = append(moduleAttributes, option.ToXmlAttribute())
+			}
+		}
+		if withAttributes, hasAttributes := module.(afterShotModuleWithAttributes); hasAttributes {
+			moduleAttributes = append(moduleAttributes, withAttributes.xmlAttributes()...)
+		}
+
+		sort.Slice(moduleAttributes, func(i, j int) bool {
+			return moduleAttributes[i].Name.Local < moduleAttributes[j].Name.Local
+		})
+		attributes = append(attributes, moduleAttributes...)
+	}
+	return attributes
+}
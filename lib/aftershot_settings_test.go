@@ -0,0 +1,55 @@
+package lib
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestOptionValuesAreClampedToTheirRange(t *testing.T) {
+	tests := []struct {
+		option     AfterShotOption
+		value      float64
+		serialized string
+		warning    string
+	}{
+		{newAfterShotInt("scont", -100, 100, 0), 42.4, "42", ""},
+		{newAfterShotInt("scont", -100, 100, 0), -100, "-100", ""},
+		{newAfterShotInt("scont", -100, 100, 0), 150, "100", "[WARN] Value 150 for option 'scont' is out of range [-100, 100] and will be clamped to 100"},
+		{newAfterShotInt("scont", -100, 100, 0), -120.5, "-100", "[WARN] Value -120.5 for option 'scont' is out of range [-100, 100] and will be clamped to -100"},
+		{newAfterShotFloat("fillamount", 0, 1, 0), 0.1234567, "0.123457", ""},
+		{newAfterShotFloat("fillamount", 0, 1, 0), -0.25, "0", "[WARN] Value -0.25 for option 'fillamount' is out of range [0, 1] and will be clamped to 0"},
+		{newAfterShotBool("lc_enabled"), 1, "true", ""},
+	}
+
+	for _, test := range tests {
+		option := test.option
+		output := captureLog(func() {
+			option.Set(test.value)
+		})
+
+		if !option.IsSet || option.Serialize() != test.serialized {
+			t.Errorf("%s = %v: serialized to '%s', expected '%s'", option.Name, test.value, option.Serialize(), test.serialized)
+		}
+		if test.warning == "" && output != "" || !strings.Contains(output, test.warning) {
+			t.Errorf("%s = %v: expected the log '%s', got '%s'", option.Name, test.value, test.warning, output)
+		}
+	}
+}
+
+// Lightroom allows a wider exposure range than aftershot
+func TestConvertedValuesAreClamped(t *testing.T) {
+	lightroom := NewLightroomPreset()
+	lightroom.Set("Exposure2012", "+5.00")
+
+	var preset AfterShotPreset
+	output := captureLog(func() {
+		preset = NewAftershotPresetFromLightroom(lightroom)
+	})
+
+	if preset.Settings.Basic.Exposure.Serialize() != "4" {
+		t.Errorf("expected the exposure to be clamped to 4, got %s", preset.Settings.Basic.Exposure.Serialize())
+	}
+	if !strings.Contains(output, "[WARN] Value 5 for option 'exposureval' is out of range [-4, 4] and will be clamped to 4") {
+		t.Errorf("expected a warning about the clamped exposure, got '%s'", output)
+	}
+}
@@ -0,0 +1,95 @@
+package lib
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Describes a version of aftershot that presets are generated for.
+// The version decides on the values of the settings header and on the options
+// that can be used in the preset. The versions differ in the settings version, the
+// bundled plugins and the noise removal. The options of aftershot itself, the layer name
+// and respectsTransform are the same in all of them.
+type AfterShotTarget struct {
+	Name            string
+	SettingsVersion string
+
+	// Plugins that are available in this version. Options of plugins are prefixed with
+	// the plugin name (e.g. `Equalizer_kb.kbs_redhue`).
+	Plugins map[string]bool
+
+	// The noise reduction module of this edition (see AFTERSHOT_NOISE_MODULE_*)
+	NoiseModule string
+}
+
+// Noise Ninja based noise removal of the pro editions, options are prefixed with `nn_`
+const AFTERSHOT_NOISE_MODULE_NOISE_NINJA = "noiseninja"
+
+// The edition has no noise removal that presets can configure
+const AFTERSHOT_NOISE_MODULE_NONE = ""
+
+var AFTERSHOT_TARGETS = map[string]AfterShotTarget{
+	"asp2": {
+		Name:            "asp2",
+		SettingsVersion: "60",
+
+		// The color equalizer only ships with AfterShot Pro 3
+		Plugins: map[string]bool{
+			"WaveletSharpen2": true,
+		},
+		NoiseModule: AFTERSHOT_NOISE_MODULE_NOISE_NINJA,
+	},
+	"asp3": {
+		Name:            "asp3",
+		SettingsVersion: "66",
+		Plugins: map[string]bool{
+			"Equalizer_kb":    true,
+			"WaveletSharpen2": true,
+		},
+		NoiseModule: AFTERSHOT_NOISE_MODULE_NOISE_NINJA,
+	},
+
+	// The standard edition of AfterShot 3 uses the same settings as the pro edition
+	// but does not contain the noise removal.
+	"as3": {
+		Name:            "as3",
+		SettingsVersion: "66",
+		Plugins: map[string]bool{
+			"Equalizer_kb":    true,
+			"WaveletSharpen2": true,
+		},
+		NoiseModule: AFTERSHOT_NOISE_MODULE_NONE,
+	},
+}
+
+const AFTERSHOT_DEFAULT_TARGET = "asp3"
+
+func NewAfterShotTarget(name string) (AfterShotTarget, error) {
+	target, exists := AFTERSHOT_TARGETS[name]
+	if !exists {
+		names := []string{}
+		for name := range AFTERSHOT_TARGETS {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		return target, fmt.Errorf("unknown aftershot target '%s', must be one of %s", name, strings.Join(names, ", "))
+	}
+
+	return target, nil
+}
+
+// Checks if the given option (e.g. `scont` or `Equalizer_kb.kbs_redhue`) can be used with this target
+func (self AfterShotTarget) SupportsOption(name string) bool {
+	if strings.HasPrefix(name, "nn_") {
+		return self.NoiseModule == AFTERSHOT_NOISE_MODULE_NOISE_NINJA
+	}
+
+	dot := strings.Index(name, ".")
+	if dot == -1 {
+		return true
+	}
+
+	return self.Plugins[name[:dot]]
+}
@@ -0,0 +1,136 @@
+package lib
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+var updateGoldenFiles = flag.Bool("update", false, "update the golden files in testdata")
+
+func convertTestPreset(t *testing.T, path string) []byte {
+	lightroom := readTestLightroomPreset(t, path)
+
+	output := bytes.Buffer{}
+	_, err := NewAftershotPresetFromLightroom(lightroom).WriteTo(&output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return output.Bytes()
+}
+
+// Converting the same preset has to produce the same bytes every time, so that converted
+// presets can be kept under version control.
+func TestConversionIsDeterministic(t *testing.T) {
+	input := filepath.Join("testdata", "preset.xmp")
+	golden := filepath.Join("testdata", "preset.aftershot.xmp")
+
+	first := convertTestPreset(t, input)
+	second := convertTestPreset(t, input)
+	if !bytes.Equal(first, second) {
+		t.Fatalf("converting %s twice produced different presets", input)
+	}
+
+	if *updateGoldenFiles {
+		err := ioutil.WriteFile(golden, first, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expected, err := ioutil.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(first, expected) {
+		t.Errorf("converted preset differs from %s, run `go test ./lib -update` after checking the changes:\n%s", golden, first)
+	}
+}
+
+func TestBreakOptionAttributes(t *testing.T) {
+	document := "<a>\n    <blay:options bopt:sat=\"-5\" bopt:vibe=\"12\"></blay:options>\n    <b c=\"d\"></b>\n</a>"
+	expected := "<a>\n    <blay:options\n        bopt:sat=\"-5\"\n        bopt:vibe=\"12\"></blay:options>\n    <b c=\"d\"></b>\n</a>"
+
+	broken := string(breakOptionAttributes([]byte(document)))
+	if broken != expected {
+		t.Errorf("got\n%s\nexpected\n%s", broken, expected)
+	}
+
+	// Only attributes of option elements are broken, values are never changed
+	document = "<rdf:Description blay:name=\"a bopt:sat=&#34;1&#34;\">\n<blay:options bopt:x=\" bopt:y\" bopt:sat=\"-5\"></blay:options>"
+	expected = "<rdf:Description blay:name=\"a bopt:sat=&#34;1&#34;\">\n<blay:options\n    bopt:x=\" bopt:y\"\n    bopt:sat=\"-5\"></blay:options>"
+	broken = string(breakOptionAttributes([]byte(document)))
+	if broken != expected {
+		t.Errorf("got\n%s\nexpected\n%s", broken, expected)
+	}
+}
+
+func TestLayerNamesAreWrittenUnchanged(t *testing.T) {
+	name := `Filter bopt:sat="1" <"quoted">`
+	preset := newTestAfterShotPreset()
+	layer := NewAfterShotLayer(name)
+	layer.Settings.Exposure.Set(0.5)
+	preset.Layers = append(preset.Layers, layer)
+
+	output := bytes.Buffer{}
+	_, err := preset.WriteTo(&output)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The name is an attribute of the layer description
+	document := struct {
+		Descriptions []struct {
+			Name string `xml:"http://www.bibblelabs.com/BibbleLayers/5.0/ name,attr"`
+		} `xml:"RDF>Description>settings>Description>layers>Seq>li>Description"`
+	}{}
+	err = xml.Unmarshal(output.Bytes(), &document)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(document.Descriptions) != 2 || document.Descriptions[1].Name != name {
+		t.Errorf("expected the layer name '%s', got %+v", name, document.Descriptions)
+	}
+}
+
+// Accepts a limited number of bytes and fails afterwards, like a full disk
+type failingWriter struct {
+	remaining int
+}
+
+func (self *failingWriter) Write(data []byte) (int, error) {
+	if len(data) > self.remaining {
+		written := self.remaining
+		self.remaining = 0
+		return written, errors.New("disk full")
+	}
+
+	self.remaining -= len(data)
+	return len(data), nil
+}
+
+func TestWriteToReturnsErrorsOfTheWriter(t *testing.T) {
+	preset := newTestAfterShotPreset()
+	preset.Settings.Basic.Saturation.Set(-5)
+
+	complete := bytes.Buffer{}
+	_, err := preset.WriteTo(&complete)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// While writing the header, the document and the final line break
+	for _, remaining := range []int{0, len(xml.Header) + 10, complete.Len() - 1} {
+		written, err := preset.WriteTo(&failingWriter{remaining: remaining})
+		if err == nil || err.Error() != "disk full" {
+			t.Errorf("%d bytes left: expected the error of the writer, got %v", remaining, err)
+		}
+		if written != int64(remaining) {
+			t.Errorf("%d bytes left: reported %d written bytes", remaining, written)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package lib
+
+import (
+	"math"
+)
+
+// Converts a color with components in the range of 0 - 1 to hue (in degrees), saturation and value
+func rgbToHsv(rgb [3]float64) (float64, float64, float64) {
+	max := math.Max(rgb[0], math.Max(rgb[1], rgb[2]))
+	min := math.Min(rgb[0], math.Min(rgb[1], rgb[2]))
+	delta := max - min
+
+	if max == 0 || delta == 0 {
+		return 0, 0, max
+	}
+
+	var hue float64
+	switch max {
+	case rgb[0]:
+		hue = (rgb[1] - rgb[2]) / delta
+	case rgb[1]:
+		hue = 2 + (rgb[2]-rgb[0])/delta
+	default:
+		hue = 4 + (rgb[0]-rgb[1])/delta
+	}
+
+	return normalizeHue(hue * 60), delta / max, max
+}
+
+func hsvToRgb(hue float64, saturation float64, value float64) [3]float64 {
+	hue = normalizeHue(hue) / 60
+	chroma := value * saturation
+	x := chroma * (1 - math.Abs(math.Mod(hue, 2)-1))
+
+	var rgb [3]float64
+	switch int(hue) {
+	case 0:
+		rgb = [3]float64{chroma, x, 0}
+	case 1:
+		rgb = [3]float64{x, chroma, 0}
+	case 2:
+		rgb = [3]float64{0, chroma, x}
+	case 3:
+		rgb = [3]float64{0, x, chroma}
+	case 4:
+		rgb = [3]float64{x, 0, chroma}
+	default:
+		rgb = [3]float64{chroma, 0, x}
+	}
+
+	for index := range rgb {
+		rgb[index] += value - chroma
+	}
+	return rgb
+}
+
+// Returns the hue in the range of 0 - 360 degrees
+func normalizeHue(hue float64) float64 {
+	hue = math.Mod(hue, 360)
+	if hue < 0 {
+		hue += 360
+	}
+	return hue
+}
+
+// Returns the shortest signed distance from one hue to another, in degrees
+func hueDistance(from float64, to float64) float64 {
+	distance := normalizeHue(to - from)
+	if distance > 180 {
+		distance -= 360
+	}
+	return distance
+}
+
+func clampColor(rgb [3]float64) [3]float64 {
+	for index := range rgb {
+		rgb[index] = math.Max(0, math.Min(1, rgb[index]))
+	}
+	return rgb
+}
+
+func colorDistance(a [3]float64, b [3]float64) float64 {
+	return math.Sqrt((a[0]-b[0])*(a[0]-b[0]) + (a[1]-b[1])*(a[1]-b[1]) + (a[2]-b[2])*(a[2]-b[2]))
+}
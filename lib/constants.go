@@ -3,3 +3,9 @@ package lib
 const AFTERSHOT_NUM_POINTS = 20
 const AFTERSHOT_CURVE_MAX = 65535
 const LIGHTROOM_CURVE_MAX = 255
+
+// Namespace of the camera raw settings (`crs:`) that contain the develop settings
+const LIGHTROOM_CRS_NAMESPACE = "http://ns.adobe.com/camera-raw-settings/1.0/"
+
+// Namespace of the tiff properties (`tiff:`), which contain the orientation of a photo
+const TIFF_NAMESPACE = "http://ns.adobe.com/tiff/1.0/"
@@ -1,58 +1,46 @@
 package lib
 
 import (
-	"fmt"
 	"log"
 	"math"
 	"strconv"
 )
 
-type AttributeMapper = func(preset AfterShotPreset, lightroomName string, value string) AfterShotPreset
+type AttributeMapper = func(preset AfterShotPreset, lightroomName string, value LightroomSlider) AfterShotPreset
 
 // Copies one attribute directly into another one
 func copyValueDirectly(destination string) AttributeMapper {
-	return func(preset AfterShotPreset, lightroomName string, value string) AfterShotPreset {
-		preset.Attributes[destination] = value
+	return func(preset AfterShotPreset, lightroomName string, value LightroomSlider) AfterShotPreset {
+		preset.Settings.Option(destination).Set(value.Value)
 		return preset
 	}
 }
 
 // Copies the absolute value
 func absInt(destination string) AttributeMapper {
-	return func(preset AfterShotPreset, lightroomName string, value string) AfterShotPreset {
-		valueFloat, err := strconv.ParseFloat(value, 64)
-		if err != nil {
-			log.Printf("[WARN] Could not convert %s to a float: %s", value, err)
-		}
-
-		preset.Attributes[destination] = fmt.Sprintf("%d", int(math.Abs(valueFloat)))
+	return func(preset AfterShotPreset, lightroomName string, value LightroomSlider) AfterShotPreset {
+		preset.Settings.Option(destination).Set(math.Abs(value.Value))
 		return preset
 	}
 }
 
 // Multiplies the value in the attribute with the given multiplier
 // and writes the result into another attribute.
-// Assumes that the input value is float-like
 func applyMultiplier(destination string, multiplier float64) AttributeMapper {
-	return func(preset AfterShotPreset, lightroomName string, value string) AfterShotPreset {
-		valueFloat, err := strconv.ParseFloat(value, 64)
-		if err != nil {
-			log.Printf("[WARN] Could not convert %s to a float: %s", value, err)
-		}
-
-		preset.Attributes[destination] = fmt.Sprintf("%f", valueFloat*multiplier)
+	return func(preset AfterShotPreset, lightroomName string, value LightroomSlider) AfterShotPreset {
+		preset.Settings.Option(destination).Set(value.Value * multiplier)
 		return preset
 	}
 }
 
 // Does nothing, print's a warning message
 func todo() AttributeMapper {
-	return func(preset AfterShotPreset, lightroomName string, value string) AfterShotPreset {
-		if value != "" && value != "0" {
+	return func(preset AfterShotPreset, lightroomName string, value LightroomSlider) AfterShotPreset {
+		if value.Value != 0 {
 			log.Printf(
 				"[WARN] Cannot lightroom configuration '%s' with value '%s' to aftershot",
 				lightroomName,
-				value,
+				formatNumber(value.Value),
 			)
 		}
 		return preset
@@ -61,216 +49,295 @@ func todo() AttributeMapper {
 
 // Does nothing
 func ignore() AttributeMapper {
-	return func(preset AfterShotPreset, lightroomName string, value string) AfterShotPreset {
+	return func(preset AfterShotPreset, lightroomName string, value LightroomSlider) AfterShotPreset {
 		return preset
 	}
 }
 
-func NewAftershotPresetFromLightroom(lightroom LightroomPreset) AfterShotPreset {
+func formatNumber(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+// Lightroom specific metadata that has no meaning for the conversion
+var lightroomMetadataAttributes = map[string]bool{
+	"Version":                    true,
+	"UUID":                       true,
+	"PresetType":                 true,
+	"SupportsColor":              true,
+	"SupportsOutputReferred":     true,
+	"SupportsNormalDynamicRange": true,
+	"SupportsAmount":             true,
+	"SupportsHighDynamicRange":   true,
+	"SupportsMonochrome":         true,
+	"SupportsSceneReferred":      true,
+	"OverrideLookVignette":       true,
+	"HasSettings":                true,
+	"CameraProfile":              true,
+
+	// Output size and state of the crop tool, the crop itself is in the typed settings
+	"CropWidth":                true,
+	"CropHeight":               true,
+	"CropUnit":                 true,
+	"CropConstrainAspectRatio": true,
+}
+
+const PRESET_MODE_ADDITIVE = "additive"
+const PRESET_MODE_RESET = "reset"
+
+// Options that control how presets are converted
+type ConversionOptions struct {
+	Target AfterShotTarget
 
-	emptyAttributeSet := map[string]string{
-		"bopt:scont":                       "0",
-		"bopt:highlightrecval":             "0",
-		"bopt:fillamount":                  "0",
-		"bopt:Equalizer_kb.kbs_redhue":     "0",
-		"bopt:Equalizer_kb.kbs_orangehue":  "0",
-		"bopt:Equalizer_kb.kbs_yellowhue":  "0",
-		"bopt:Equalizer_kb.kbs_greenhue":   "0",
-		"bopt:Equalizer_kb.kbs_cyanhue":    "0",
-		"bopt:Equalizer_kb.kbs_bluehue":    "0",
-		"bopt:Equalizer_kb.kbs_magentahue": "0",
-		"bopt:Equalizer_kb.kbs_redsat":     "0",
-		"bopt:Equalizer_kb.kbs_orangesat":  "0",
-		"bopt:Equalizer_kb.kbs_yellowsat":  "0",
-		"bopt:Equalizer_kb.kbs_greensat":   "0",
-		"bopt:Equalizer_kb.kbs_cyansat":    "0",
-		"bopt:Equalizer_kb.kbs_bluesat":    "0",
-		"bopt:Equalizer_kb.kbs_magentasat": "0",
-		"bopt:Equalizer_kb.kbs_redlum":     "0",
-		"bopt:Equalizer_kb.kbs_orangelum":  "0",
-		"bopt:Equalizer_kb.kbs_yellowlum":  "0",
-		"bopt:Equalizer_kb.kbs_greenlum":   "0",
-		"bopt:Equalizer_kb.kbs_cyanlum":    "0",
-		"bopt:Equalizer_kb.kbs_bluelum":    "0",
-		"bopt:Equalizer_kb.kbs_magentalum": "0",
-		"bopt:Equalizer_kb.kbs_enabled":    "true",
+	// Additive presets only contain the options changed by the lightroom preset,
+	// reset presets contain neutral values for every other option.
+	Mode string
+
+	// Leaves out perspective and rotation (including the straightening of a kept crop), which
+	// only make sense for the photo the preset was created from.
+	ExcludeGeometry bool
+
+	// Converts the crop and straightening of a photo. Looks are usually copied without them.
+	IncludeCrop bool
+}
+
+func NewConversionOptions() ConversionOptions {
+	return ConversionOptions{
+		Target:          AFTERSHOT_TARGETS[AFTERSHOT_DEFAULT_TARGET],
+		Mode:            PRESET_MODE_ADDITIVE,
+		ExcludeGeometry: false,
+		IncludeCrop:     false,
 	}
+}
+
+func NewAftershotPresetFromLightroom(lightroom LightroomPreset) AfterShotPreset {
+	return NewAftershotPresetFromLightroomWithOptions(lightroom, NewConversionOptions())
+}
+
+func NewAftershotPresetFromLightroomWithOptions(lightroom LightroomPreset, options ConversionOptions) AfterShotPreset {
 
 	// Attribute mappers are used to map lightroom attributes to aftershot attributes.
 	// Keys correspond to attribute names in lightroom configuration, values correspond to
 	// attribute mapper functions (see above)
 	attributeMappers := map[string]AttributeMapper{
-		"Contrast2012":   copyValueDirectly("bopt:scont"),
-		"Highlights2012": absInt("bopt:highlightrecval"),
+		// Both are in stops, aftershot has a smaller range
+		"Exposure2012":   copyValueDirectly("exposureval"),
+		"Contrast2012":   copyValueDirectly("scont"),
+		"Highlights2012": absInt("highlightrecval"),
 
 		// Lightroom and Aftershot use a vastly differing scale.
-		"Shadows2012": applyMultiplier("bopt:fillamount", 0.01),
-
-		"Whites2012":  todo(),
-		"Blacks2012":  todo(),
-		"Clarity2021": todo(),
-		"Vibrance":    copyValueDirectly("bopt:vibe"),
-		"Saturation":  copyValueDirectly("bopt:sat"),
-
-		// Through trial and errror I discovered that 100 in lightroom is roughly equal to 70 in aftershot
-		"HueAdjustmentRed":     applyMultiplier("bopt:Equalizer_kb.kbs_redhue", 0.7),
-		"HueAdjustmentOrange":  applyMultiplier("bopt:Equalizer_kb.kbs_orangehue", 0.7),
-		"HueAdjustmentYellow":  applyMultiplier("bopt:Equalizer_kb.kbs_yellowhue", 0.7),
-		"HueAdjustmentGreen":   applyMultiplier("bopt:Equalizer_kb.kbs_greenhue", 0.7),
-		"HueAdjustmentAqua":    applyMultiplier("bopt:Equalizer_kb.kbs_cyanhue", 0.7),
-		"HueAdjustmentBlue":    applyMultiplier("bopt:Equalizer_kb.kbs_bluehue", 0.7),
-		"HueAdjustmentMagenta": applyMultiplier("bopt:Equalizer_kb.kbs_magentahue", 0.7),
-
-		// Saturation values seem to be 1:1
-		"SaturationAdjustmentRed":     copyValueDirectly("bopt:Equalizer_kb.kbs_redsat"),
-		"SaturationAdjustmentOrange":  copyValueDirectly("bopt:Equalizer_kb.kbs_orangesat"),
-		"SaturationAdjustmentYellow":  copyValueDirectly("bopt:Equalizer_kb.kbs_yellowsat"),
-		"SaturationAdjustmentGreen":   copyValueDirectly("bopt:Equalizer_kb.kbs_greensat"),
-		"SaturationAdjustmentAqua":    copyValueDirectly("bopt:Equalizer_kb.kbs_cyansat"),
-		"SaturationAdjustmentBlue":    copyValueDirectly("bopt:Equalizer_kb.kbs_bluesat"),
-		"SaturationAdjustmentMagenta": copyValueDirectly("bopt:Equalizer_kb.kbs_magentasat"),
-
-		// Luminance seems to be 1:1
-		"LuminanceAdjustmentRed":     copyValueDirectly("bopt:Equalizer_kb.kbs_redlum"),
-		"LuminanceAdjustmentOrange":  copyValueDirectly("bopt:Equalizer_kb.kbs_orangelum"),
-		"LuminanceAdjustmentYellow":  copyValueDirectly("bopt:Equalizer_kb.kbs_yellowlum"),
-		"LuminanceAdjustmentGreen":   copyValueDirectly("bopt:Equalizer_kb.kbs_greenlum"),
-		"LuminanceAdjustmentAqua":    copyValueDirectly("bopt:Equalizer_kb.kbs_cyanlum"),
-		"LuminanceAdjustmentBlue":    copyValueDirectly("bopt:Equalizer_kb.kbs_bluelum"),
-		"LuminanceAdjustmentMagenta": copyValueDirectly("bopt:Equalizer_kb.kbs_magentalum"),
-
-		// Sharpness: Ignore detailed configuration. Main sharpness configuration defaults to 50 in lightroom and 100 in aftershot
-		"Sharpness":     applyMultiplier("bopt:newsharpen", 2),
-		"SharpenRadius": ignore(),
-		"SharpenDetail": ignore(),
-
-		// Lightroom specific metadata
-		"Version":                    ignore(),
-		"UUID":                       ignore(),
-		"PresetType":                 ignore(),
-		"crs":                        ignore(),
-		"ProcessVersion":             ignore(),
-		"SupportsColor":              ignore(),
-		"SupportsOutputReferred":     ignore(),
-		"SupportsNormalDynamicRange": ignore(),
-		"SupportsAmount":             ignore(),
-		"SupportsHighDynamicRange":   ignore(),
-		"SupportsMonochrome":         ignore(),
-		"SupportsSceneReferred":      ignore(),
-		"OverrideLookVignette":       ignore(),
-		"HasSettings":                ignore(),
-		"ToneCurveName2012":          ignore(),
-		"CameraProfile":              ignore(),
+		"Shadows2012": applyMultiplier("fillamount", 0.01),
+
+		"Whites2012": todo(),
+		// No aftershot option for the black point is known. Legacy presets set it through 'Shadows'.
+		"Blacks2012": func(preset AfterShotPreset, lightroomName string, value LightroomSlider) AfterShotPreset {
+			log.Printf("[WARN] The black point cannot be converted to aftershot, %s (%s) will be ignored.", lightroomName, formatNumber(value.Value))
+			return preset
+		},
+		"Vibrance":   copyValueDirectly("vibe"),
+		"Saturation": copyValueDirectly("sat"),
 
 		// Handled in pass
-		"ConvertToGrayscale":            ignore(),
-		"Texture":                       ignore(),
-		"SplitToningBalance":            ignore(),
-		"SplitToningShadowSaturation":   ignore(),
-		"SplitToningShadowHue":          ignore(),
-		"GrainAmount":                   ignore(),
-		"GrainFrequency":                ignore(),
-		"GrainSize":                     ignore(),
-		"ColorNoiseReduction":           ignore(),
-		"ColorNoiseReductionSmoothness": ignore(),
-		"ColorNoiseReductionDetail":     ignore(),
-		"Dehaze":                        ignore(),
-		"ParametricShadows":             ignore(),
-		"ParametricDarks":               ignore(),
-		"ParametricLights":              ignore(),
-		"ParametricHighlights":          ignore(),
-		"ParametricShadowSplit":         ignore(),
-		"ParametricMidtoneSplit":        ignore(),
-		"ParametricHighlightSplit":      ignore(),
-		"HueAdjustmentPurple":           ignore(),
-		"SaturationAdjustmentPurple":    ignore(),
-		"LuminanceAdjustmentPurple":     ignore(),
+		"Sharpness":                         ignore(),
+		"SharpenRadius":                     ignore(),
+		"SharpenDetail":                     ignore(),
+		"SharpenEdgeMasking":                ignore(),
+		"Texture":                           ignore(),
+		"Clarity2012":                       ignore(),
+		"SplitToningBalance":                ignore(),
+		"SplitToningShadowSaturation":       ignore(),
+		"SplitToningShadowHue":              ignore(),
+		"SplitToningHighlightSaturation":    ignore(),
+		"SplitToningHighlightHue":           ignore(),
+		"ColorGradeMidtoneHue":              ignore(),
+		"ColorGradeMidtoneSat":              ignore(),
+		"GrayMixerRed":                      ignore(),
+		"GrayMixerOrange":                   ignore(),
+		"GrayMixerYellow":                   ignore(),
+		"GrayMixerGreen":                    ignore(),
+		"GrayMixerAqua":                     ignore(),
+		"GrayMixerBlue":                     ignore(),
+		"GrayMixerPurple":                   ignore(),
+		"GrayMixerMagenta":                  ignore(),
+		"GrainAmount":                       ignore(),
+		"GrainFrequency":                    ignore(),
+		"GrainSize":                         ignore(),
+		"LuminanceSmoothing":                ignore(),
+		"LuminanceNoiseReductionDetail":     ignore(),
+		"LuminanceNoiseReductionContrast":   ignore(),
+		"ColorNoiseReduction":               ignore(),
+		"ColorNoiseReductionSmoothness":     ignore(),
+		"ColorNoiseReductionDetail":         ignore(),
+		"PostCropVignetteAmount":            ignore(),
+		"PostCropVignetteMidpoint":          ignore(),
+		"PostCropVignetteRoundness":         ignore(),
+		"PostCropVignetteFeather":           ignore(),
+		"PostCropVignetteHighlightContrast": ignore(),
+		"PostCropVignetteStyle":             ignore(),
+		"VignetteAmount":                    ignore(),
+		"VignetteMidpoint":                  ignore(),
+		"LensProfileEnable":                 ignore(),
+		"AutoLateralCA":                     ignore(),
+		"LensManualDistortionAmount":        ignore(),
+		"DefringePurpleAmount":              ignore(),
+		"DefringePurpleHueLo":               ignore(),
+		"DefringePurpleHueHi":               ignore(),
+		"DefringeGreenAmount":               ignore(),
+		"DefringeGreenHueLo":                ignore(),
+		"DefringeGreenHueHi":                ignore(),
+		"PerspectiveVertical":               ignore(),
+		"PerspectiveHorizontal":             ignore(),
+		"PerspectiveRotate":                 ignore(),
+		"PerspectiveScale":                  ignore(),
+		"PerspectiveAspect":                 ignore(),
+		"PerspectiveX":                      ignore(),
+		"PerspectiveY":                      ignore(),
+		"PerspectiveUpright":                ignore(),
+		"CropTop":                           ignore(),
+		"CropLeft":                          ignore(),
+		"CropBottom":                        ignore(),
+		"CropRight":                         ignore(),
+		"CropAngle":                         ignore(),
+		"CropConstrainToWarp":               ignore(),
+		"ShadowTint":                        ignore(),
+		"RedHue":                            ignore(),
+		"RedSaturation":                     ignore(),
+		"GreenHue":                          ignore(),
+		"GreenSaturation":                   ignore(),
+		"BlueHue":                           ignore(),
+		"BlueSaturation":                    ignore(),
+		"Dehaze":                            ignore(),
+		"ParametricShadows":                 ignore(),
+		"ParametricDarks":                   ignore(),
+		"ParametricLights":                  ignore(),
+		"ParametricHighlights":              ignore(),
+		"ParametricShadowSplit":             ignore(),
+		"ParametricMidtoneSplit":            ignore(),
+		"ParametricHighlightSplit":          ignore(),
+		"HueAdjustmentRed":                  ignore(),
+		"HueAdjustmentOrange":               ignore(),
+		"HueAdjustmentYellow":               ignore(),
+		"HueAdjustmentGreen":                ignore(),
+		"HueAdjustmentAqua":                 ignore(),
+		"HueAdjustmentBlue":                 ignore(),
+		"HueAdjustmentPurple":               ignore(),
+		"HueAdjustmentMagenta":              ignore(),
+		"SaturationAdjustmentRed":           ignore(),
+		"SaturationAdjustmentOrange":        ignore(),
+		"SaturationAdjustmentYellow":        ignore(),
+		"SaturationAdjustmentGreen":         ignore(),
+		"SaturationAdjustmentAqua":          ignore(),
+		"SaturationAdjustmentBlue":          ignore(),
+		"SaturationAdjustmentPurple":        ignore(),
+		"SaturationAdjustmentMagenta":       ignore(),
+		"LuminanceAdjustmentRed":            ignore(),
+		"LuminanceAdjustmentOrange":         ignore(),
+		"LuminanceAdjustmentYellow":         ignore(),
+		"LuminanceAdjustmentGreen":          ignore(),
+		"LuminanceAdjustmentAqua":           ignore(),
+		"LuminanceAdjustmentBlue":           ignore(),
+		"LuminanceAdjustmentPurple":         ignore(),
+		"LuminanceAdjustmentMagenta":        ignore(),
 
 		// Ignored
-		"ColorGradeMidtoneHue": ignore(),
-		"ColorGradeBlending":   ignore(),
-		"ColorGradeMidtoneSat": ignore(),
+		"ColorGradeBlending": ignore(),
 	}
 
 	// Custom passes that are applied to the preset after the attribute mapping (see above)
 	// has finished. This can be used to add more involved logic.
 	postMappingPasses := []func(LightroomPreset, AfterShotPreset) AfterShotPreset{
 
-		// ConvertToGrayscale = 0 saturation
-		func(lightroom LightroomPreset, preset AfterShotPreset) AfterShotPreset {
-			if lightroom.Attributes["ConvertToGrayscale"] == "True" {
-				preset.Attributes["bopt:sat"] = "0"
-			}
-			return preset
-		},
+		// ConvertToGrayscale = 0 saturation, gray mixer to the color equalizer
+		convertMonochrome,
 
-		// Texture to wavelet sharpen USM in clarity mode
-		func(lightroom LightroomPreset, preset AfterShotPreset) AfterShotPreset {
-			if lightroom.Attributes["Texture"] != "0" {
-				log.Printf("[INFO] Texture is translated to usage of the wavelet sharpen plugin. Make sure you have that plugin installed")
-				preset.Attributes["bopt:WaveletSharpen2.bSphWaveletUsmon"] = "true"
-				preset.Attributes["bopt:WaveletSharpen2.bSphWaveletUsmClarity"] = "true"
+		// Split toning and color grading to the channel curves
+		convertToning,
 
-				// Approximated value. TODO: Compare with lightroom rendering
-				preset.Attributes["bopt:WaveletSharpen2.bSphWaveletUsmRadius"] = "10"
+		// HSL adjustments to the color equalizer
+		convertHsl,
 
-				preset.Attributes["bopt:WaveletSharpen2.bSphWaveletUsmAmount"] = lightroom.Attributes["Texture"]
-			}
-			return preset
-		},
+		// Texture, clarity and dehaze to local contrast / wavelet sharpen
+		convertLocalContrast,
+
+		// Sharpening, after local contrast which has priority on the wavelet sharpen plugin
+		convertSharpening,
+
+		// Luminance and color noise reduction to the noise removal of the target
+		convertNoiseReduction,
+
+		// Post crop vignette and lens vignetting
+		convertVignette,
+
+		// Grain to a generated texture
+		convertGrain,
 
-		// Dehaze to local contrast
+		// Camera calibration to the color equalizer and channel curves, added to the HSL adjustments
+		convertCalibration,
+
+		// Graduated and radial filters to layers with regions
+		convertLocalAdjustments,
+
+		// Lens corrections and transform
 		func(lightroom LightroomPreset, preset AfterShotPreset) AfterShotPreset {
-			if lightroom.Attributes["Dehaze"] != "0" {
-				preset.Attributes["bopt:lc_enabled"] = "true"
-				preset.Attributes["bopt:lc_strength"] = lightroom.Attributes["Dehaze"]
+			return convertGeometry(lightroom, preset, options.ExcludeGeometry)
+		},
 
-			}
-			return preset
+		// Crop and straightening of photos
+		func(lightroom LightroomPreset, preset AfterShotPreset) AfterShotPreset {
+			return convertCrop(lightroom, preset, options.IncludeCrop, options.ExcludeGeometry)
 		},
 
 		// Non supported features in aftershot
-		// Aftershot does not support split toning
 		func(lightroom LightroomPreset, preset AfterShotPreset) AfterShotPreset {
-			if lightroom.Attributes["SplitToningBalance"] != "+50" || lightroom.Attributes["SplitToningShadowSaturation"] == "0" {
-				log.Printf("[WARN] This preset seems to use split toning. Split toning is not supported by aftershot and will be ignored.")
-			}
-			if lightroom.Attributes["GrainAmount"] != "+50" {
-				log.Printf("[WARN] This preset seems to use grain. Grain is not supported by aftershot and will be ignored.")
-			}
-			if lightroom.Attributes["ColorNoiseReduction"] != "25" {
-				log.Printf("[WARN] This preset seems to use color noise reduction. This is not supported in Aftershot and will be ignored.")
-			}
-			if lightroom.Attributes["ParametricShadowSplit"] != "25" || lightroom.Attributes["ParametricMidtoneSplit"] != "50" || lightroom.Attributes["ParametricHighlightSplit"] != "75" {
+			settings := lightroom.Settings
+			if settings.IsChanged("ParametricShadowSplit") || settings.IsChanged("ParametricMidtoneSplit") || settings.IsChanged("ParametricHighlightSplit") {
 				log.Printf("[WARN] This preset seems to use parametric splits. This is not supported in Aftershot and will be ignored.")
 			}
 
-			log.Printf("[INFO] Lightroom has 7 Adjustable colors, aftershot has 6. Purple will be ignored if it has any settings.")
-
 			return preset
 		},
+
+		// Options that do not exist in the targeted aftershot version
+		removeUnsupportedOptions,
 	}
 
+	lightroom.Settings = lightroom.Settings.UpgradedToProcessVersion2012()
+	lightroom.Settings = lightroom.Settings.WithNamedToneCurveExpanded()
+
 	preset := AfterShotPreset{
-		Attributes: emptyAttributeSet,
-		ToneCurve:  newAfterShotCombinedToneCurveFromLightRoomToneCurve(lightroom.ToneCurve),
+		Target:   options.Target,
+		Settings: NewAfterShotSettings(),
 	}
-	preset.Attributes = map[string]string{
-		"bopt:Equalizer_kb.kbs_enabled": "true",
-		"bopt:curveson":                 "true",
+	if options.Mode == PRESET_MODE_RESET {
+		preset.Settings.Reset()
 	}
+	preset.Settings.Curves.ToneCurve = newAfterShotCombinedToneCurveFromLightRoomToneCurve(lightroom.Settings.ToneCurve)
+	preset.Settings.Curves.Enabled.SetBool(true)
+	preset.Settings.Equalizer.Enabled.SetBool(true)
 
-	for key, value := range lightroom.Attributes {
-		if value == "" {
-			continue
+	lightroom.Settings.EachSlider(func(name string, value LightroomSlider) {
+		if !lightroom.Settings.IsChanged(name) {
+			return
 		}
 
-		mapper, mapperExists := attributeMappers[key]
+		mapper, mapperExists := attributeMappers[name]
 		if !mapperExists {
 			mapper = todo()
 		}
 
-		preset = mapper(preset, key, value)
+		preset = mapper(preset, name, value)
+	})
+
+	// Settings which are not understood by the converter at all
+	for key, value := range lightroom.Attributes {
+		if value == "" || value == "0" || lightroomMetadataAttributes[key] {
+			continue
+		}
+
+		log.Printf(
+			"[WARN] Cannot lightroom configuration '%s' with value '%s' to aftershot",
+			key,
+			value,
+		)
 	}
 
 	for _, pass := range postMappingPasses {
@@ -280,31 +347,98 @@ func NewAftershotPresetFromLightroom(lightroom LightroomPreset) AfterShotPreset
 	return preset
 }
 
-func newAfterShotToneCurvePointFromLightroomToneCurvePoint(lightroom LightroomToneCurvePoint) AfterShotToneCurvePoint {
-	multiplier := AFTERSHOT_CURVE_MAX / LIGHTROOM_CURVE_MAX
+// Scales a curve value of lightroom (0 - 255, possibly fractional) to the 16 bit range of aftershot
+func scaleLightroomCurveValue(value float64) float64 {
+	return value * AFTERSHOT_CURVE_MAX / LIGHTROOM_CURVE_MAX
+}
+
+// Rounds a scaled curve value to the nearest 16 bit level
+func roundAfterShotCurveValue(value float64) int {
+	return int(math.Max(0, math.Min(math.Round(value), AFTERSHOT_CURVE_MAX)))
+}
 
+func newAfterShotToneCurvePointFromLightroomToneCurvePoint(lightroom LightroomToneCurvePoint) AfterShotToneCurvePoint {
 	return AfterShotToneCurvePoint{
-		In:  lightroom.In * multiplier,
-		Out: lightroom.Out * multiplier,
+		In:  roundAfterShotCurveValue(scaleLightroomCurveValue(lightroom.In)),
+		Out: roundAfterShotCurveValue(scaleLightroomCurveValue(lightroom.Out)),
 	}
 }
 
+// Largest difference (in 16 bit levels) between the lightroom curve and the converted curve that is accepted
+// without adding points, which is half of a lightroom (8 bit) level.
+const CURVE_RESAMPLING_TOLERANCE = float64(AFTERSHOT_CURVE_MAX) / LIGHTROOM_CURVE_MAX / 2
+
+// Lightroom and aftershot interpolate differently between the points of a curve, so the points of
+// the aftershot curve are chosen to follow the interpolated lightroom curve instead of copying them.
 func newAfterShotToneCurveChannelFromLightRoomToneCurveChannel(lightroom LightroomToneCurve) AfterShotToneCurveChannel {
 	points := make([]AfterShotToneCurvePoint, len(lightroom.Points))
+	initial := make([][2]float64, len(lightroom.Points))
 	for index, lightroomPoint := range lightroom.Points {
 		points[index] = newAfterShotToneCurvePointFromLightroomToneCurvePoint(lightroomPoint)
+		initial[index] = [2]float64{scaleLightroomCurveValue(lightroomPoint.In), scaleLightroomCurveValue(lightroomPoint.Out)}
+	}
+	if len(points) < 2 {
+		return newAfterShotToneCurveChannel(points)
+	}
+
+	curve := newLightroomCurveInterpolation(initial, AFTERSHOT_CURVE_MAX)
+	resampled, difference := resampleCurve(curve, initial, AFTERSHOT_NUM_POINTS, CURVE_RESAMPLING_TOLERANCE)
+	if difference > CURVE_RESAMPLING_TOLERANCE {
+		log.Printf(
+			"[WARN] The tone curve can only be approximated with %d points and differs by up to %s levels (0 - 255).",
+			AFTERSHOT_NUM_POINTS,
+			formatNumber(math.Round(difference/scaleLightroomCurveValue(1))),
+		)
 	}
 
-	return AfterShotToneCurveChannel{
-		Points: points,
+	points = make([]AfterShotToneCurvePoint, len(resampled))
+	for index, point := range resampled {
+		points[index] = AfterShotToneCurvePoint{In: roundAfterShotCurveValue(point[0]), Out: roundAfterShotCurveValue(point[1])}
 	}
+	return newAfterShotToneCurveChannel(points)
 }
 
 func newAfterShotCombinedToneCurveFromLightRoomToneCurve(lightroom LightroomCombinedToneCurve) AfterShotCombinedToneCurve {
-	return AfterShotCombinedToneCurve{
-		Rgb:   newAfterShotToneCurveChannelFromLightRoomToneCurveChannel(lightroom.Rgb),
-		Red:   newAfterShotToneCurveChannelFromLightRoomToneCurveChannel(lightroom.Red),
-		Green: newAfterShotToneCurveChannelFromLightRoomToneCurveChannel(lightroom.Green),
-		Blue:  newAfterShotToneCurveChannelFromLightRoomToneCurveChannel(lightroom.Blue),
+	curve := NewAfterShotCombinedToneCurve()
+	curve.Rgb = newAfterShotToneCurveChannelFromLightRoomToneCurveChannel(lightroom.Rgb)
+	curve.Red = newAfterShotToneCurveChannelFromLightRoomToneCurveChannel(lightroom.Red)
+	curve.Green = newAfterShotToneCurveChannelFromLightRoomToneCurveChannel(lightroom.Green)
+	curve.Blue = newAfterShotToneCurveChannelFromLightRoomToneCurveChannel(lightroom.Blue)
+	return curve
+}
+
+// Unsets the options that do not exist in the targeted aftershot version, in the main layer as well
+// as in the additional layers. Layers without any option left are removed.
+func removeUnsupportedOptions(lightroom LightroomPreset, preset AfterShotPreset) AfterShotPreset {
+	// Returns whether any option is left
+	filter := func(options []*AfterShotOption) bool {
+		remaining := false
+		for _, option := range options {
+			if !option.IsSet || preset.Target.SupportsOption(option.Name) {
+				remaining = remaining || option.IsSet
+				continue
+			}
+
+			// Neutral values of reset presets are not worth a warning
+			if !option.IsNeutral() {
+				log.Printf("[WARN] Option '%s' with value '%s' is not available in target %s and will be ignored.", option.Name, option.Serialize(), preset.Target.Name)
+			}
+			option.Unset()
+		}
+		return remaining
 	}
+
+	filter(preset.Settings.Options())
+
+	var layers []AfterShotLayer
+	for _, layer := range preset.Layers {
+		if !filter(layer.Settings.Options()) {
+			log.Printf("[WARN] %s has no options left that are available in target %s and will be ignored.", layer.Name, preset.Target.Name)
+			continue
+		}
+		layers = append(layers, layer)
+	}
+	preset.Layers = layers
+
+	return preset
 }
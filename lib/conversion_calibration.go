@@ -0,0 +1,172 @@
+package lib
+
+import (
+	"log"
+	"math"
+)
+
+// Rotation of a primary in degrees at a hue value of 100 in the calibration panel
+const LIGHTROOM_CALIBRATION_HUE_DEGREES = 30
+
+// Relative saturation change of a primary at a saturation value of 100 in the calibration panel
+const LIGHTROOM_CALIBRATION_SATURATION = 0.5
+
+// Saturation and value of the colors the approximation is measured with
+const CALIBRATION_TEST_SATURATION = 0.8
+const CALIBRATION_TEST_VALUE = 0.8
+
+// Shift of an equalizer band (in its units) below which the band is not changed. Smaller
+// shifts are lost when the values are rounded anyway.
+const CALIBRATION_MIN_SHIFT = 0.5
+
+// Average remaining error (in 8 bit levels) above which a calibration is reported as inaccurate
+const CALIBRATION_MAX_ERROR = 8
+
+var calibrationPrimaries = []struct {
+	Name string
+	Hue  float64
+}{
+	{"Red", 0},
+	{"Green", 120},
+	{"Blue", 240},
+}
+
+// Lightroom calibrates by moving the primaries of the camera color space. This is modelled
+// as a matrix whose columns are the rotated and (de)saturated primaries. Rows are normalized
+// so that neutral colors stay neutral.
+type calibrationMatrix [3][3]float64
+
+func newCalibrationMatrix(settings LightroomDevelopSettings) calibrationMatrix {
+	matrix := calibrationMatrix{}
+	for column, primary := range calibrationPrimaries {
+		hue := primary.Hue + settings.Value(primary.Name+"Hue")/100*LIGHTROOM_CALIBRATION_HUE_DEGREES
+		saturation := 1 + settings.Value(primary.Name+"Saturation")/100*LIGHTROOM_CALIBRATION_SATURATION
+
+		rotated := hsvToRgb(hue, 1, 1)
+		gray := (rotated[0] + rotated[1] + rotated[2]) / 3
+		for row := range rotated {
+			matrix[row][column] = gray + saturation*(rotated[row]-gray)
+		}
+	}
+
+	for row := range matrix {
+		sum := matrix[row][0] + matrix[row][1] + matrix[row][2]
+		for column := range matrix[row] {
+			matrix[row][column] /= sum
+		}
+	}
+
+	return matrix
+}
+
+func (self calibrationMatrix) apply(rgb [3]float64) [3]float64 {
+	result := [3]float64{}
+	for row := range self {
+		result[row] = self[row][0]*rgb[0] + self[row][1]*rgb[1] + self[row][2]*rgb[2]
+	}
+	return clampColor(result)
+}
+
+// Change of a color of a certain hue, as the color equalizer expresses it
+type equalizerShift struct {
+	Hue        float64
+	Saturation float64
+}
+
+// Interpolates the shifts of the two equalizer bands surrounding the given hue
+func interpolateEqualizerShift(shifts [AFTERSHOT_EQUALIZER_NUM_BANDS]equalizerShift, hue float64) equalizerShift {
+	for index := range AFTERSHOT_EQUALIZER_BAND_HUES {
+		next := (index + 1) % AFTERSHOT_EQUALIZER_NUM_BANDS
+		start := AFTERSHOT_EQUALIZER_BAND_HUES[index]
+		width := normalizeHue(AFTERSHOT_EQUALIZER_BAND_HUES[next] - start)
+		offset := normalizeHue(hue - start)
+		if offset < width {
+			weight := offset / width
+			return equalizerShift{
+				Hue:        shifts[index].Hue*(1-weight) + shifts[next].Hue*weight,
+				Saturation: shifts[index].Saturation*(1-weight) + shifts[next].Saturation*weight,
+			}
+		}
+	}
+	return shifts[0]
+}
+
+// Approximates camera calibration with the color equalizer (primaries) and the green channel
+// curve (shadow tint).
+func convertCalibration(lightroom LightroomPreset, preset AfterShotPreset) AfterShotPreset {
+	settings := lightroom.Settings
+
+	primariesChanged := false
+	for _, primary := range calibrationPrimaries {
+		primariesChanged = primariesChanged || settings.IsChanged(primary.Name+"Hue") || settings.IsChanged(primary.Name+"Saturation")
+	}
+
+	if primariesChanged {
+		matrix := newCalibrationMatrix(settings)
+
+		// Measure what the calibration does to the center of every equalizer band
+		shifts := [AFTERSHOT_EQUALIZER_NUM_BANDS]equalizerShift{}
+		for index, bandHue := range AFTERSHOT_EQUALIZER_BAND_HUES {
+			calibrated := matrix.apply(hsvToRgb(bandHue, CALIBRATION_TEST_SATURATION, CALIBRATION_TEST_VALUE))
+			hue, saturation, _ := rgbToHsv(calibrated)
+			shifts[index] = equalizerShift{
+				Hue:        hueDistance(bandHue, hue),
+				Saturation: saturation/CALIBRATION_TEST_SATURATION - 1,
+			}
+
+			// Lightroom calibrates before the HSL adjustments. Both end up in the same bands of the
+			// equalizer, so the calibration is added to the HSL adjustments converted before.
+			// Bands the calibration does not move are left alone, so additive presets keep them.
+			equalizer := &preset.Settings.Equalizer
+			hueShift := shifts[index].Hue / AFTERSHOT_EQUALIZER_HUE_DEGREES * 100
+			if math.Abs(hueShift) >= CALIBRATION_MIN_SHIFT {
+				equalizer.Hue[index].Set(equalizer.Hue[index].Value + hueShift)
+			}
+			saturationShift := shifts[index].Saturation * 100
+			if math.Abs(saturationShift) >= CALIBRATION_MIN_SHIFT {
+				equalizer.Saturation[index].Set(equalizer.Saturation[index].Value + saturationShift)
+			}
+		}
+
+		// Compare the calibration to the approximation around the color wheel
+		remaining := 0.0
+		samples := 0
+		for hue := 0.0; hue < 360; hue += 10 {
+			color := hsvToRgb(hue, CALIBRATION_TEST_SATURATION, CALIBRATION_TEST_VALUE)
+			shift := interpolateEqualizerShift(shifts, hue)
+			approximated := hsvToRgb(
+				hue+shift.Hue,
+				math.Max(0, math.Min(1, CALIBRATION_TEST_SATURATION*(1+shift.Saturation))),
+				CALIBRATION_TEST_VALUE,
+			)
+
+			remaining += colorDistance(matrix.apply(color), approximated) * 255
+			samples++
+		}
+		remaining /= float64(samples)
+
+		if remaining > CALIBRATION_MAX_ERROR {
+			log.Printf("[WARN] Camera calibration is approximated through the color equalizer with an average error of %s levels. Colors will differ noticeably.", formatNumber(math.Round(remaining)))
+		} else {
+			log.Printf("[INFO] Camera calibration is approximated through the color equalizer with an average error of %s levels.", formatNumber(math.Round(remaining)))
+		}
+	}
+
+	if settings.IsChanged("ShadowTint") {
+		green := &preset.Settings.Curves.ToneCurve.Green
+		if !green.isIdentity() {
+			log.Printf("[WARN] The preset uses a green channel curve, ShadowTint cannot be added to it and will be ignored.")
+			return preset
+		}
+
+		// Magenta (positive) tint removes green from the shadows
+		shadows := AFTERSHOT_CURVE_MAX / 4
+		green.Points = []AfterShotToneCurvePoint{
+			{In: 0, Out: 0},
+			{In: shadows, Out: int(math.Round(float64(shadows) * (1 - settings.Value("ShadowTint")/100*0.25)))},
+			{In: AFTERSHOT_CURVE_MAX, Out: AFTERSHOT_CURVE_MAX},
+		}
+	}
+
+	return preset
+}
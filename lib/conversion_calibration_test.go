@@ -0,0 +1,93 @@
+package lib
+
+import (
+	"testing"
+)
+
+func TestAfterShotToneCurveChannelIsIdentity(t *testing.T) {
+	tests := []struct {
+		channel  AfterShotToneCurveChannel
+		expected bool
+	}{
+		{newAfterShotToneCurveChannel(nil), true},
+		{newAfterShotToneCurveChannel([]AfterShotToneCurvePoint{{0, 0}, {65535, 65535}}), true},
+		{newAfterShotToneCurveChannel([]AfterShotToneCurvePoint{{0, 0}, {32768, 32768}, {65535, 65535}}), true},
+		{newAfterShotToneCurveChannel([]AfterShotToneCurvePoint{{0, 0}, {32768, 30000}, {65535, 65535}}), false},
+		{newAfterShotToneCurveChannel([]AfterShotToneCurvePoint{{0, 1000}, {65535, 65535}}), false},
+		{AfterShotToneCurveChannel{InputGamma: 1.2, InputWhite: AFTERSHOT_CURVE_MAX, OutputWhite: AFTERSHOT_CURVE_MAX}, false},
+	}
+
+	for _, test := range tests {
+		if test.channel.isIdentity() != test.expected {
+			t.Errorf("isIdentity() of %+v should be %v", test.channel, test.expected)
+		}
+	}
+}
+
+func TestConvertCalibrationShadowTint(t *testing.T) {
+	lightroom := NewLightroomPreset()
+	lightroom.Set("ShadowTint", "20")
+
+	// Lightroom presets often contain linear channel curves, which are not a reason to skip the tint
+	preset := newTestAfterShotPreset()
+	preset.Settings.Curves.ToneCurve.Green = newAfterShotToneCurveChannel([]AfterShotToneCurvePoint{{0, 0}, {65535, 65535}})
+	preset = convertCalibration(lightroom, preset)
+
+	green := preset.Settings.Curves.ToneCurve.Green
+	if len(green.Points) != 3 || green.Points[1].Out >= green.Points[1].In {
+		t.Errorf("magenta shadow tint should lower the green shadows, got %+v", green.Points)
+	}
+
+	curved := newAfterShotToneCurveChannel([]AfterShotToneCurvePoint{{0, 0}, {32768, 40000}, {65535, 65535}})
+	preset = newTestAfterShotPreset()
+	preset.Settings.Curves.ToneCurve.Green = curved
+	preset = convertCalibration(lightroom, preset)
+	if len(preset.Settings.Curves.ToneCurve.Green.Points) != 3 || preset.Settings.Curves.ToneCurve.Green.Points[1] != curved.Points[1] {
+		t.Errorf("an existing green curve should be kept, got %+v", preset.Settings.Curves.ToneCurve.Green.Points)
+	}
+}
+
+func TestConvertCalibrationAddsToHslAdjustments(t *testing.T) {
+	lightroom := NewLightroomPreset()
+	lightroom.Set("SaturationAdjustmentRed", "10")
+	lightroom.Set("RedSaturation", "40")
+
+	hslOnly := convertHsl(lightroom, newTestAfterShotPreset())
+	both := convertCalibration(lightroom, hslOnly)
+
+	calibrationOnly := convertCalibration(lightroom, newTestAfterShotPreset())
+	red := both.Settings.Equalizer.Saturation[0].Value
+	expected := hslOnly.Settings.Equalizer.Saturation[0].Value + calibrationOnly.Settings.Equalizer.Saturation[0].Value
+	if red != expected {
+		t.Errorf("red saturation = %v, expected %v", red, expected)
+	}
+}
+
+func TestConvertCalibrationKeepsUntouchedBands(t *testing.T) {
+	lightroom := NewLightroomPreset()
+	lightroom.Set("RedSaturation", "40")
+
+	// Saturating the red primary does not rotate any hue
+	equalizer := convertCalibration(lightroom, newTestAfterShotPreset()).Settings.Equalizer
+	for index, band := range AFTERSHOT_EQUALIZER_BANDS {
+		if equalizer.Hue[index].IsSet {
+			t.Errorf("hue of %s should stay unset, got %s", band, equalizer.Hue[index].Serialize())
+		}
+		if !equalizer.Saturation[index].IsSet {
+			t.Errorf("saturation of %s should be set", band)
+		}
+	}
+
+	// Rotating the green primary leaves red and cyan alone
+	lightroom = NewLightroomPreset()
+	lightroom.Set("GreenHue", "40")
+	equalizer = convertCalibration(lightroom, newTestAfterShotPreset()).Settings.Equalizer
+	for _, index := range []int{0, 4} {
+		if equalizer.Hue[index].IsSet || equalizer.Saturation[index].IsSet {
+			t.Errorf("%s should stay unset, got hue %s and saturation %s", AFTERSHOT_EQUALIZER_BANDS[index], equalizer.Hue[index].Serialize(), equalizer.Saturation[index].Serialize())
+		}
+	}
+	if !equalizer.Hue[3].IsSet {
+		t.Errorf("hue of green should be set")
+	}
+}
@@ -0,0 +1,89 @@
+package lib
+
+import (
+	"log"
+	"math"
+)
+
+// Maps a point of the image as it is stored to the image as it is displayed, for every
+// EXIF orientation. Mirrored orientations also mirror the direction of rotations.
+var exifOrientations = map[int]struct {
+	Transform func(x float64, y float64) (float64, float64)
+	Mirrored  bool
+}{
+	1: {func(x, y float64) (float64, float64) { return x, y }, false},
+	2: {func(x, y float64) (float64, float64) { return 1 - x, y }, true},
+	3: {func(x, y float64) (float64, float64) { return 1 - x, 1 - y }, false},
+	4: {func(x, y float64) (float64, float64) { return x, 1 - y }, true},
+	5: {func(x, y float64) (float64, float64) { return y, x }, true},
+	6: {func(x, y float64) (float64, float64) { return 1 - y, x }, false},
+	7: {func(x, y float64) (float64, float64) { return 1 - y, 1 - x }, true},
+	8: {func(x, y float64) (float64, float64) { return y, 1 - x }, false},
+}
+
+var lightroomCropSettings = []string{
+	"CropTop",
+	"CropLeft",
+	"CropBottom",
+	"CropRight",
+	"CropAngle",
+}
+
+// Converts the crop of a photo. Lightroom stores the crop relative to the image before the
+// EXIF orientation is applied and rotates clockwise for positive angles. The straightening
+// angle is rotation as well, so it is left out together with the perspective.
+func convertCrop(lightroom LightroomPreset, preset AfterShotPreset, includeCrop bool, excludeGeometry bool) AfterShotPreset {
+	settings := lightroom.Settings
+
+	cropped := settings.HasCrop.Value
+	for _, name := range lightroomCropSettings {
+		cropped = cropped || settings.IsChanged(name)
+	}
+	if !cropped {
+		return preset
+	}
+	if !includeCrop {
+		log.Printf("[INFO] The crop of the preset is not converted, it is only included on request")
+		return preset
+	}
+
+	orientation, known := exifOrientations[lightroom.Orientation]
+	if !known {
+		if lightroom.Orientation != 0 {
+			log.Printf("[WARN] Unknown orientation %d, the crop is converted as if the photo was not rotated.", lightroom.Orientation)
+		}
+		orientation = exifOrientations[1]
+	}
+
+	left, top := orientation.Transform(settings.Value("CropLeft"), settings.Value("CropTop"))
+	right, bottom := orientation.Transform(settings.Value("CropRight"), settings.Value("CropBottom"))
+	crop := AfterShotCrop{
+		Enabled: true,
+		Left:    math.Max(0, math.Min(left, right)),
+		Top:     math.Max(0, math.Min(top, bottom)),
+		Right:   math.Min(1, math.Max(left, right)),
+		Bottom:  math.Min(1, math.Max(top, bottom)),
+		Angle:   -settings.Value("CropAngle"),
+	}
+	if orientation.Mirrored {
+		crop.Angle = -crop.Angle
+	}
+	if excludeGeometry && settings.IsChanged("CropAngle") {
+		log.Printf("[INFO] The straightening of the crop is excluded from the conversion")
+		crop.Angle = 0
+	}
+
+	if crop.Right <= crop.Left || crop.Bottom <= crop.Top {
+		log.Printf("[WARN] The crop of the preset is empty and will be ignored.")
+		return preset
+	}
+
+	// Aftershot keeps the crop where it is when the geometry changes
+	if settings.Value("CropConstrainToWarp") != 0 && preset.RespectsTransform {
+		log.Printf("[WARN] Aftershot does not constrain the crop to the corrected image, blank edges may become visible.")
+	}
+
+	preset.Crop = crop
+	preset.RespectsTransform = true
+	return preset
+}
@@ -0,0 +1,80 @@
+package lib
+
+import (
+	"math"
+	"testing"
+)
+
+func newTestCroppedPreset() LightroomPreset {
+	lightroom := NewLightroomPreset()
+	lightroom.Set("HasCrop", "True")
+	lightroom.Set("CropLeft", "0.1")
+	lightroom.Set("CropTop", "0.2")
+	lightroom.Set("CropRight", "0.9")
+	lightroom.Set("CropBottom", "0.8")
+	lightroom.Set("CropAngle", "2.5")
+	return lightroom
+}
+
+func TestCropIsOnlyIncludedOnRequest(t *testing.T) {
+	preset := convertCrop(newTestCroppedPreset(), newTestAfterShotPreset(), false, false)
+	if preset.Crop.Enabled {
+		t.Errorf("crop should not be converted without being requested")
+	}
+}
+
+func TestCropAngleRespectsExcludeGeometry(t *testing.T) {
+	tests := []struct {
+		excludeGeometry bool
+		angle           float64
+	}{
+		{false, -2.5},
+		{true, 0},
+	}
+
+	for _, test := range tests {
+		preset := convertCrop(newTestCroppedPreset(), newTestAfterShotPreset(), true, test.excludeGeometry)
+		if !preset.Crop.Enabled {
+			t.Fatalf("excludeGeometry %v: the crop should be converted", test.excludeGeometry)
+		}
+		if preset.Crop.Angle != test.angle {
+			t.Errorf("excludeGeometry %v: angle = %v, expected %v", test.excludeGeometry, preset.Crop.Angle, test.angle)
+		}
+		if preset.Crop.Left != 0.1 || preset.Crop.Top != 0.2 || preset.Crop.Right != 0.9 || preset.Crop.Bottom != 0.8 {
+			t.Errorf("excludeGeometry %v: unexpected crop %+v", test.excludeGeometry, preset.Crop)
+		}
+	}
+}
+
+func TestCropFollowsTheOrientation(t *testing.T) {
+	tests := []struct {
+		orientation int
+		expected    AfterShotCrop
+	}{
+		{0, AfterShotCrop{Left: 0.1, Top: 0.2, Right: 0.7, Bottom: 0.9, Angle: -2.5}},
+		{1, AfterShotCrop{Left: 0.1, Top: 0.2, Right: 0.7, Bottom: 0.9, Angle: -2.5}},
+		{3, AfterShotCrop{Left: 0.3, Top: 0.1, Right: 0.9, Bottom: 0.8, Angle: -2.5}},
+		{6, AfterShotCrop{Left: 0.1, Top: 0.1, Right: 0.8, Bottom: 0.7, Angle: -2.5}},
+		{8, AfterShotCrop{Left: 0.2, Top: 0.3, Right: 0.9, Bottom: 0.9, Angle: -2.5}},
+
+		// Mirroring also mirrors the direction of the rotation
+		{2, AfterShotCrop{Left: 0.3, Top: 0.2, Right: 0.9, Bottom: 0.9, Angle: 2.5}},
+	}
+
+	for _, test := range tests {
+		lightroom := newTestCroppedPreset()
+		lightroom.Set("CropRight", "0.7")
+		lightroom.Set("CropBottom", "0.9")
+		lightroom.Orientation = test.orientation
+
+		crop := convertCrop(lightroom, newTestAfterShotPreset(), true, false).Crop
+		actual := []float64{crop.Left, crop.Top, crop.Right, crop.Bottom, crop.Angle}
+		expected := []float64{test.expected.Left, test.expected.Top, test.expected.Right, test.expected.Bottom, test.expected.Angle}
+		for index := range actual {
+			if math.Abs(actual[index]-expected[index]) > 1e-9 {
+				t.Errorf("orientation %d: crop = %+v, expected %+v", test.orientation, crop, test.expected)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package lib
+
+import (
+	"log"
+	"math"
+)
+
+// Perspective and rotation of a single photo which are part of presets by accident more often than not
+var lightroomPerImageGeometry = []string{
+	"PerspectiveVertical",
+	"PerspectiveHorizontal",
+	"PerspectiveRotate",
+	"PerspectiveScale",
+	"PerspectiveAspect",
+	"PerspectiveX",
+	"PerspectiveY",
+	"PerspectiveUpright",
+}
+
+// Lightroom defringes purple and green separately with an amount of 0 - 20 each,
+// aftershot has a single defringe strength of 0 - 100.
+const LIGHTROOM_DEFRINGE_MULTIPLIER = 5
+
+// Maps lens corrections and transforms. The preset only respects the transform settings
+// of aftershot if it changes the geometry of the image.
+func convertGeometry(lightroom LightroomPreset, preset AfterShotPreset, excludeGeometry bool) AfterShotPreset {
+	settings := lightroom.Settings
+	lens := &preset.Settings.Lens
+	transform := &preset.Settings.Transform
+
+	if settings.IsChanged("LensProfileEnable") {
+		lens.Enabled.SetBool(true)
+		lens.Profile.SetBool(settings.Value("LensProfileEnable") != 0)
+	}
+	if settings.IsChanged("AutoLateralCA") {
+		lens.Enabled.SetBool(true)
+		lens.ChromaticAberration.SetBool(settings.Value("AutoLateralCA") != 0)
+	}
+	// Both tools use a range of -100 - 100. Strength and direction are assumed to be the same,
+	// the 1:1 scale has not been measured.
+	if settings.IsChanged("LensManualDistortionAmount") {
+		lens.Enabled.SetBool(true)
+		lens.Distortion.Set(settings.Value("LensManualDistortionAmount"))
+	}
+
+	if settings.IsChanged("DefringePurpleAmount") || settings.IsChanged("DefringeGreenAmount") {
+		lens.Enabled.SetBool(true)
+		lens.Defringe.Set(math.Max(settings.Value("DefringePurpleAmount"), settings.Value("DefringeGreenAmount")) * LIGHTROOM_DEFRINGE_MULTIPLIER)
+
+		for _, name := range []string{"DefringePurpleHueLo", "DefringePurpleHueHi", "DefringeGreenHueLo", "DefringeGreenHueHi"} {
+			if settings.IsChanged(name) {
+				log.Printf("[WARN] Aftershot does not support defringe hue ranges, %s will be ignored.", name)
+			}
+		}
+	}
+
+	perImageGeometry := false
+	for _, name := range lightroomPerImageGeometry {
+		perImageGeometry = perImageGeometry || settings.IsChanged(name)
+	}
+
+	if perImageGeometry && excludeGeometry {
+		log.Printf("[INFO] Perspective and rotation of the preset are excluded from the conversion")
+	} else if perImageGeometry {
+		transform.Enabled.SetBool(true)
+		transform.Vertical.Set(settings.Value("PerspectiveVertical"))
+		transform.Horizontal.Set(settings.Value("PerspectiveHorizontal"))
+		transform.Rotate.Set(settings.Value("PerspectiveRotate"))
+		transform.Scale.Set(settings.Value("PerspectiveScale"))
+		transform.Aspect.Set(settings.Value("PerspectiveAspect"))
+
+		if settings.IsChanged("PerspectiveX") || settings.IsChanged("PerspectiveY") {
+			log.Printf("[WARN] Aftershot cannot offset the image after the perspective correction, PerspectiveX and PerspectiveY will be ignored.")
+		}
+
+		// Upright analyses the photo, the resulting correction is not stored in the preset
+		if settings.IsChanged("PerspectiveUpright") {
+			log.Printf("[WARN] Upright modes of lightroom cannot be converted and will be ignored.")
+		}
+	}
+
+	preset.RespectsTransform = lens.Profile.Value != 0 ||
+		lens.Distortion.IsSet && !lens.Distortion.IsNeutral() ||
+		transform.Enabled.Value != 0
+
+	return preset
+}
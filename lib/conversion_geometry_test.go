@@ -0,0 +1,89 @@
+package lib
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Converts a preset with lens corrections and a perspective correction with and without
+// the geometry and compares the lens and transform options of the result.
+func TestConvertGeometryRespectsExcludeGeometry(t *testing.T) {
+	lightroom := NewLightroomPreset()
+	for name, value := range map[string]string{
+		"LensProfileEnable":          "1",
+		"AutoLateralCA":              "1",
+		"LensManualDistortionAmount": "-20",
+		"DefringePurpleAmount":       "4",
+		"PerspectiveVertical":        "10",
+		"PerspectiveRotate":          "1.5",
+		"PerspectiveScale":           "110",
+	} {
+		lightroom.Set(name, value)
+	}
+
+	lens := map[string]string{
+		"lens_enabled":    "true",
+		"lens_profile":    "true",
+		"lens_autoca":     "true",
+		"lens_distortion": "-20",
+		"lens_defringe":   "20",
+	}
+	transform := map[string]string{
+		"persp_enabled":    "true",
+		"persp_vertical":   "10",
+		"persp_horizontal": "0",
+		"persp_rotate":     "1.5",
+		"persp_scale":      "110",
+		"persp_aspect":     "0",
+	}
+
+	tests := []struct {
+		excludeGeometry bool
+		expected        map[string]string
+	}{
+		{false, mergeOptions(lens, transform)},
+		{true, lens},
+	}
+
+	for _, test := range tests {
+		options := NewConversionOptions()
+		options.ExcludeGeometry = test.excludeGeometry
+
+		var preset AfterShotPreset
+		output := captureLog(func() {
+			preset = NewAftershotPresetFromLightroomWithOptions(lightroom, options)
+		})
+
+		actual := map[string]string{}
+		for name, value := range setOptions(preset.Settings) {
+			if strings.HasPrefix(name, "lens_") || strings.HasPrefix(name, "persp_") {
+				actual[name] = value
+			}
+		}
+		if !reflect.DeepEqual(actual, test.expected) {
+			t.Errorf("excludeGeometry %v: set options %v, expected %v", test.excludeGeometry, actual, test.expected)
+		}
+
+		// The lens profile changes the geometry either way
+		if !preset.RespectsTransform {
+			t.Errorf("excludeGeometry %v: the preset should respect the transform", test.excludeGeometry)
+		}
+
+		excluded := strings.Contains(output, "[INFO] Perspective and rotation of the preset are excluded from the conversion")
+		if excluded != test.excludeGeometry {
+			t.Errorf("excludeGeometry %v: unexpected log '%s'", test.excludeGeometry, output)
+		}
+	}
+}
+
+func mergeOptions(first map[string]string, second map[string]string) map[string]string {
+	merged := map[string]string{}
+	for name, value := range first {
+		merged[name] = value
+	}
+	for name, value := range second {
+		merged[name] = value
+	}
+	return merged
+}
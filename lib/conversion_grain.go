@@ -0,0 +1,18 @@
+package lib
+
+import (
+	"log"
+)
+
+// Aftershot has no grain settings that a preset could contain. A grain texture is generated
+// instead, which has to be added as an overlay layer by hand.
+func convertGrain(lightroom LightroomPreset, preset AfterShotPreset) AfterShotPreset {
+	settings := lightroom.Settings
+	if !settings.IsChanged("GrainAmount") {
+		return preset
+	}
+
+	log.Printf("[WARN] Aftershot presets cannot contain grain. A grain texture is generated instead, it has to be added manually as an overlay layer in soft light mode.")
+	preset.GrainOverlay = NewGrainTexture(settings.Value("GrainAmount"), settings.Value("GrainSize"), settings.Value("GrainFrequency"))
+	return preset
+}
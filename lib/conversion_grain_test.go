@@ -0,0 +1,29 @@
+package lib
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestConvertGrainGeneratesATexture(t *testing.T) {
+	lightroom := NewLightroomPreset()
+	lightroom.Set("GrainAmount", "30")
+	lightroom.Set("GrainSize", "25")
+	lightroom.Set("GrainFrequency", "50")
+
+	preset := convertGrain(lightroom, newTestAfterShotPreset())
+	if preset.GrainOverlay == nil {
+		t.Fatalf("expected a grain texture")
+	}
+
+	for _, attribute := range preset.Settings.ToXmlAttributes() {
+		if strings.Contains(strings.ToLower(attribute.Name.Local), "grain") {
+			t.Errorf("presets cannot contain grain, but %s is written", attribute.Name.Local)
+		}
+	}
+
+	preset = convertGrain(NewLightroomPreset(), newTestAfterShotPreset())
+	if preset.GrainOverlay != nil {
+		t.Errorf("presets without grain should not get a texture")
+	}
+}
@@ -0,0 +1,97 @@
+package lib
+
+import (
+	"math"
+)
+
+const LIGHTROOM_HSL_NUM_BANDS = 8
+
+// Bands of the lightroom HSL panel, in order of their hue
+var LIGHTROOM_HSL_BANDS = [LIGHTROOM_HSL_NUM_BANDS]string{"Red", "Orange", "Yellow", "Green", "Aqua", "Blue", "Purple", "Magenta"}
+
+// Center hue of the bands in degrees, in the same order as LIGHTROOM_HSL_BANDS
+var LIGHTROOM_HSL_BAND_HUES = [LIGHTROOM_HSL_NUM_BANDS]float64{0, 30, 60, 120, 180, 240, 270, 300}
+
+// Through trial and errror I discovered that a hue of 100 in lightroom is roughly equal to 70 in aftershot.
+// Saturation and luminance seem to be 1:1.
+const LIGHTROOM_HSL_HUE_MULTIPLIER = 0.7
+
+// Response of a band to the given hue. Bands of both tools fall off linearly towards the
+// centers of their neighbours, so the responses of all bands add up to 1 for every hue.
+func bandResponse(centers []float64, index int, hue float64) float64 {
+	center := centers[index]
+	previous := centers[(index+len(centers)-1)%len(centers)]
+	next := centers[(index+1)%len(centers)]
+
+	distance := hueDistance(center, hue)
+	if distance < 0 {
+		return math.Max(0, 1+distance/normalizeHue(center-previous))
+	}
+	return math.Max(0, 1-distance/normalizeHue(next-center))
+}
+
+// Calculates how much every lightroom band contributes to every aftershot band.
+// The contribution is the response of the aftershot band at the center of the lightroom band,
+// normalized so that every lightroom band is distributed completely. Bands that exist in both
+// tools map 1:1 while bands that only exist in lightroom (purple) are split between the
+// aftershot bands overlapping them (half to blue, half to magenta).
+func newHslBandWeights() [AFTERSHOT_EQUALIZER_NUM_BANDS][LIGHTROOM_HSL_NUM_BANDS]float64 {
+	weights := [AFTERSHOT_EQUALIZER_NUM_BANDS][LIGHTROOM_HSL_NUM_BANDS]float64{}
+	for lightroom, hue := range LIGHTROOM_HSL_BAND_HUES {
+		total := 0.0
+		for aftershot := range AFTERSHOT_EQUALIZER_BAND_HUES {
+			weights[aftershot][lightroom] = bandResponse(AFTERSHOT_EQUALIZER_BAND_HUES[:], aftershot, hue)
+			total += weights[aftershot][lightroom]
+		}
+
+		for aftershot := range AFTERSHOT_EQUALIZER_BAND_HUES {
+			weights[aftershot][lightroom] /= total
+		}
+	}
+	return weights
+}
+
+var hslBandWeights = newHslBandWeights()
+
+// Projects the lightroom HSL bands onto the bands of the color equalizer
+func convertHsl(lightroom LightroomPreset, preset AfterShotPreset) AfterShotPreset {
+	settings := lightroom.Settings
+
+	// Black and white photos use the gray mixer instead
+	if settings.ConvertToGrayscale.Value {
+		return preset
+	}
+	equalizer := &preset.Settings.Equalizer
+
+	adjustments := []struct {
+		Prefix     string
+		Multiplier float64
+		Options    *[AFTERSHOT_EQUALIZER_NUM_BANDS]AfterShotOption
+	}{
+		{"HueAdjustment", LIGHTROOM_HSL_HUE_MULTIPLIER, &equalizer.Hue},
+		{"SaturationAdjustment", 1, &equalizer.Saturation},
+		{"LuminanceAdjustment", 1, &equalizer.Luminance},
+	}
+
+	for _, adjustment := range adjustments {
+		for aftershot := range AFTERSHOT_EQUALIZER_BAND_HUES {
+			value := 0.0
+			changed := false
+			for lightroom, band := range LIGHTROOM_HSL_BANDS {
+				weight := hslBandWeights[aftershot][lightroom]
+				if weight == 0 || !settings.IsChanged(adjustment.Prefix+band) {
+					continue
+				}
+
+				value += weight * settings.Value(adjustment.Prefix+band) * adjustment.Multiplier
+				changed = true
+			}
+
+			if changed {
+				adjustment.Options[aftershot].Set(value)
+			}
+		}
+	}
+
+	return preset
+}
@@ -0,0 +1,58 @@
+package lib
+
+import (
+	"math"
+	"testing"
+)
+
+func TestHslBandWeights(t *testing.T) {
+	expected := map[string]map[int]float64{
+		"Red":     {0: 1},
+		"Orange":  {1: 1},
+		"Yellow":  {2: 1},
+		"Green":   {3: 1},
+		"Aqua":    {4: 1},
+		"Blue":    {5: 1},
+		"Purple":  {5: 0.5, 6: 0.5},
+		"Magenta": {6: 1},
+	}
+
+	for lightroom, band := range LIGHTROOM_HSL_BANDS {
+		for aftershot := range AFTERSHOT_EQUALIZER_BAND_HUES {
+			weight := hslBandWeights[aftershot][lightroom]
+			if math.Abs(weight-expected[band][aftershot]) > 1e-9 {
+				t.Errorf("%s contributes %v to aftershot band %d, expected %v", band, weight, aftershot, expected[band][aftershot])
+			}
+		}
+	}
+}
+
+func TestConvertHslMapsSharedBandsOneToOne(t *testing.T) {
+	lightroom := NewLightroomPreset()
+	lightroom.Set("SaturationAdjustmentBlue", "-40")
+	lightroom.Set("LuminanceAdjustmentPurple", "30")
+	lightroom.Set("HueAdjustmentRed", "100")
+
+	preset := convertHsl(lightroom, newTestAfterShotPreset())
+	equalizer := preset.Settings.Equalizer
+
+	tests := []struct {
+		name     string
+		option   AfterShotOption
+		expected float64
+	}{
+		{"saturation blue", equalizer.Saturation[5], -40},
+		{"luminance blue", equalizer.Luminance[5], 15},
+		{"luminance magenta", equalizer.Luminance[6], 15},
+		{"hue red", equalizer.Hue[0], 100 * LIGHTROOM_HSL_HUE_MULTIPLIER},
+	}
+	for _, test := range tests {
+		if math.Abs(test.option.Value-test.expected) > 1e-9 {
+			t.Errorf("%s = %v, expected %v", test.name, test.option.Value, test.expected)
+		}
+	}
+
+	if equalizer.Saturation[4].IsSet || equalizer.Luminance[4].IsSet {
+		t.Errorf("aqua should not be changed")
+	}
+}
@@ -0,0 +1,184 @@
+package lib
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strings"
+)
+
+var lightroomLocalCorrectionLabels = map[string]string{
+	LIGHTROOM_LOCAL_GRADIENT:          "Graduated filter",
+	LIGHTROOM_LOCAL_CIRCULAR_GRADIENT: "Radial filter",
+	LIGHTROOM_LOCAL_PAINT:             "Brush",
+	LIGHTROOM_LOCAL_MASK_GROUP:        "Mask",
+}
+
+// Maps local settings onto the options of a layer. Returns false if the value cannot be expressed.
+type localSettingMapper = func(layer *AfterShotLayer, value float64) bool
+
+var localSettingMappers = map[string]localSettingMapper{
+	"LocalExposure2012": func(layer *AfterShotLayer, value float64) bool {
+		layer.Settings.Exposure.Set(value)
+		return true
+	},
+	"LocalContrast2012": func(layer *AfterShotLayer, value float64) bool {
+		layer.Settings.Contrast.Set(value)
+		return true
+	},
+
+	// Highlight recovery can only darken highlights, fill light can only brighten shadows
+	"LocalHighlights2012": func(layer *AfterShotLayer, value float64) bool {
+		if value > 0 {
+			return false
+		}
+		layer.Settings.HighlightRecovery.Set(-value)
+		return true
+	},
+	"LocalShadows2012": func(layer *AfterShotLayer, value float64) bool {
+		if value < 0 {
+			return false
+		}
+		layer.Settings.FillLight.Set(value * 0.01)
+		return true
+	},
+	"LocalSaturation": func(layer *AfterShotLayer, value float64) bool {
+		layer.Settings.Saturation.Set(value)
+		return true
+	},
+}
+
+// Size of the polygons that are used for the half planes of linear gradients. Needs to be
+// large enough to cover the image from any point inside of it.
+const LOCAL_GRADIENT_EXTENT = 2
+
+// Converts a linear gradient to a polygon covering everything on the side of the full effect.
+// The transition between no effect and full effect becomes the feather around the middle line.
+// Lightroom coordinates are relative to width and height, so the angles are only exact for square images.
+func newAfterShotRegionFromLinearGradient(mask LightroomCorrectionMask) (AfterShotRegion, bool) {
+	zeroX, zeroY := mask.Number("ZeroX"), mask.Number("ZeroY")
+	fullX, fullY := mask.Number("FullX"), mask.Number("FullY")
+
+	length := math.Hypot(fullX-zeroX, fullY-zeroY)
+	if length == 0 {
+		return AfterShotRegion{}, false
+	}
+
+	// Direction towards full effect and the perpendicular direction along the middle line
+	directionX, directionY := (fullX-zeroX)/length, (fullY-zeroY)/length
+	alongX, alongY := -directionY, directionX
+	middleX, middleY := (zeroX+fullX)/2, (zeroY+fullY)/2
+
+	return AfterShotRegion{
+		Kind: AFTERSHOT_REGION_POLYGON,
+		Points: [][2]float64{
+			{middleX + alongX*LOCAL_GRADIENT_EXTENT, middleY + alongY*LOCAL_GRADIENT_EXTENT},
+			{middleX + (alongX+directionX)*LOCAL_GRADIENT_EXTENT, middleY + (alongY+directionY)*LOCAL_GRADIENT_EXTENT},
+			{middleX + (directionX-alongX)*LOCAL_GRADIENT_EXTENT, middleY + (directionY-alongY)*LOCAL_GRADIENT_EXTENT},
+			{middleX - alongX*LOCAL_GRADIENT_EXTENT, middleY - alongY*LOCAL_GRADIENT_EXTENT},
+		},
+		Feather: length,
+	}, true
+}
+
+// Radial filters apply outside of the ellipse unless they are flipped. Aftershot regions apply
+// inside, so the region is inverted for regular radial filters.
+func newAfterShotRegionFromCircularGradient(mask LightroomCorrectionMask) (AfterShotRegion, bool) {
+	top, left := mask.Number("Top"), mask.Number("Left")
+	bottom, right := mask.Number("Bottom"), mask.Number("Right")
+	if right <= left || bottom <= top {
+		return AfterShotRegion{}, false
+	}
+
+	radiusX := (right - left) / 2
+	return AfterShotRegion{
+		Kind:     AFTERSHOT_REGION_CIRCLE,
+		CenterX:  (left + right) / 2,
+		CenterY:  (top + bottom) / 2,
+		RadiusX:  radiusX,
+		RadiusY:  (bottom - top) / 2,
+		Angle:    mask.Number("Angle"),
+		Feather:  mask.Number("Feather") / 100 * radiusX,
+		Inverted: !mask.Flag("Flipped"),
+	}, true
+}
+
+// Converts graduated filters, radial filters and mask groups to additional layers.
+// Everything that cannot be expressed by a layer with regions is reported.
+func convertLocalAdjustments(lightroom LightroomPreset, preset AfterShotPreset) AfterShotPreset {
+	count := map[string]int{}
+	for _, correction := range lightroom.LocalCorrections {
+		count[correction.Kind]++
+		name := correction.Name
+		if name == "" {
+			name = fmt.Sprintf("%s %d", lightroomLocalCorrectionLabels[correction.Kind], count[correction.Kind])
+		}
+
+		if !correction.Active {
+			continue
+		}
+
+		layer := NewAfterShotLayer(name)
+		unsupported := []string{}
+
+		// Sorted for reproducible output of warnings
+		settings := []string{}
+		for setting := range correction.Settings {
+			settings = append(settings, setting)
+		}
+		sort.Strings(settings)
+
+		for _, setting := range settings {
+			value := correction.Settings[setting] * correction.Amount
+			if value == 0 {
+				continue
+			}
+
+			mapper, exists := localSettingMappers[setting]
+			if !exists || !mapper(&layer, value) {
+				unsupported = append(unsupported, fmt.Sprintf("%s=%s", setting, formatNumber(value)))
+			}
+		}
+
+		for _, mask := range correction.Masks {
+			var region AfterShotRegion
+			valid := false
+
+			// Subtracting and intersecting masks of mask groups cannot be expressed by regions
+			blendMode := mask.Attributes["MaskBlendMode"]
+			if blendMode == "" || blendMode == "0" {
+				switch mask.What {
+				case LIGHTROOM_MASK_GRADIENT:
+					region, valid = newAfterShotRegionFromLinearGradient(mask)
+				case LIGHTROOM_MASK_CIRCULAR_GRADIENT:
+					region, valid = newAfterShotRegionFromCircularGradient(mask)
+				}
+			}
+
+			if valid {
+				layer.Regions = append(layer.Regions, region)
+			} else {
+				unsupported = append(unsupported, "mask "+mask.What)
+			}
+		}
+
+		if len(unsupported) > 0 {
+			log.Printf("[WARN] %s: %s cannot be expressed in aftershot and will be ignored.", name, strings.Join(unsupported, ", "))
+		}
+
+		hasSettings := false
+		for _, option := range layer.Settings.Options() {
+			hasSettings = hasSettings || option.IsSet
+		}
+		if !hasSettings || len(layer.Regions) == 0 {
+			log.Printf("[WARN] %s cannot be converted to an aftershot layer and will be ignored.", name)
+			continue
+		}
+
+		log.Printf("[INFO] %s is converted to an aftershot layer with %d region(s)", name, len(layer.Regions))
+		preset.Layers = append(preset.Layers, layer)
+	}
+
+	return preset
+}
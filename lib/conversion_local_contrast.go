@@ -0,0 +1,112 @@
+package lib
+
+import (
+	"log"
+	"math"
+	"strings"
+)
+
+const LOCAL_CONTRAST_MODULE_LOCAL_CONTRAST = "local contrast"
+const LOCAL_CONTRAST_MODULE_WAVELET_SHARPEN = "wavelet sharpen"
+
+// The request of a lightroom setting to enhance contrast at a certain scale.
+// Strength is in the range of 0 - 100, radius is in pixels.
+type localContrastContribution struct {
+	Source   string
+	Module   string
+	Strength float64
+	Radius   float64
+}
+
+// Lightroom settings that enhance local contrast, ordered from the finest to the coarsest scale.
+// Lightroom does not document the radii it uses, the values below are estimates.
+var localContrastModels = []struct {
+	Name  string
+	Model func(value float64, target AfterShotTarget) localContrastContribution
+}{
+	// Texture works on fine detail, which is what the USM clarity mode of wavelet sharpen does.
+	// Stronger texture also reaches a bit further out.
+	{"Texture", func(value float64, target AfterShotTarget) localContrastContribution {
+		module := LOCAL_CONTRAST_MODULE_WAVELET_SHARPEN
+		if !target.SupportsOption("WaveletSharpen2.bSphWaveletUsmon") {
+			module = LOCAL_CONTRAST_MODULE_LOCAL_CONTRAST
+		}
+		return localContrastContribution{Module: module, Strength: value * 0.8, Radius: 4 + value*0.06}
+	}},
+
+	// Clarity is midtone contrast at a medium radius. Aftershot is a lot stronger at the same value.
+	{"Clarity2012", func(value float64, target AfterShotTarget) localContrastContribution {
+		return localContrastContribution{Module: LOCAL_CONTRAST_MODULE_LOCAL_CONTRAST, Strength: value * 0.6, Radius: 30 + value*0.2}
+	}},
+
+	// Dehaze mostly is contrast at a very large radius
+	{"Dehaze", func(value float64, target AfterShotTarget) localContrastContribution {
+		return localContrastContribution{Module: LOCAL_CONTRAST_MODULE_LOCAL_CONTRAST, Strength: value * 0.7, Radius: 120}
+	}},
+}
+
+// Combines all contributions to the same module into one.
+// The strengths add up while the radius is the average, weighted by strength.
+func resolveLocalContrastContributions(contributions []localContrastContribution) localContrastContribution {
+	combined := localContrastContribution{Module: contributions[0].Module}
+	sources := []string{}
+	for _, contribution := range contributions {
+		combined.Strength += contribution.Strength
+		combined.Radius += contribution.Radius * contribution.Strength
+		sources = append(sources, contribution.Source)
+	}
+	combined.Radius /= combined.Strength
+	combined.Source = strings.Join(sources, ", ")
+
+	if len(contributions) > 1 {
+		log.Printf(
+			"[INFO] %s share the %s module of aftershot and are combined into strength %s at radius %s",
+			combined.Source,
+			combined.Module,
+			formatNumber(math.Round(combined.Strength)),
+			formatNumber(math.Round(combined.Radius)),
+		)
+	}
+
+	return combined
+}
+
+// Maps Texture, Clarity2012 and Dehaze onto the local contrast module and wavelet sharpen plugin
+func convertLocalContrast(lightroom LightroomPreset, preset AfterShotPreset) AfterShotPreset {
+	byModule := map[string][]localContrastContribution{}
+	for _, model := range localContrastModels {
+		if !lightroom.Settings.IsChanged(model.Name) {
+			continue
+		}
+
+		value := lightroom.Settings.Value(model.Name)
+
+		// Both aftershot modules can only add contrast
+		if value < 0 {
+			log.Printf("[WARN] Negative values of %s (%s) cannot be converted to aftershot and will be ignored.", model.Name, formatNumber(value))
+			continue
+		}
+
+		contribution := model.Model(value, preset.Target)
+		contribution.Source = model.Name
+		byModule[contribution.Module] = append(byModule[contribution.Module], contribution)
+	}
+
+	if contributions, exists := byModule[LOCAL_CONTRAST_MODULE_LOCAL_CONTRAST]; exists {
+		combined := resolveLocalContrastContributions(contributions)
+		preset.Settings.LocalContrast.Enabled.SetBool(true)
+		preset.Settings.LocalContrast.Strength.Set(math.Min(combined.Strength, 100))
+		preset.Settings.LocalContrast.Radius.Set(combined.Radius)
+	}
+
+	if contributions, exists := byModule[LOCAL_CONTRAST_MODULE_WAVELET_SHARPEN]; exists {
+		combined := resolveLocalContrastContributions(contributions)
+		log.Printf("[INFO] %s is translated to usage of the wavelet sharpen plugin. Make sure you have that plugin installed", combined.Source)
+		preset.Settings.WaveletSharpen.UsmEnabled.SetBool(true)
+		preset.Settings.WaveletSharpen.UsmClarity.SetBool(true)
+		preset.Settings.WaveletSharpen.UsmRadius.Set(combined.Radius)
+		preset.Settings.WaveletSharpen.UsmAmount.Set(math.Min(combined.Strength, 100))
+	}
+
+	return preset
+}
@@ -0,0 +1,63 @@
+package lib
+
+import (
+	"testing"
+)
+
+func TestConvertLocalContrast(t *testing.T) {
+	tests := []struct {
+		settings map[string]string
+		expected map[string]string
+	}{
+		// Unchanged and negative values add no contrast
+		{map[string]string{"Clarity2012": "0"}, map[string]string{}},
+		{map[string]string{"Clarity2012": "-30", "Dehaze": "-100"}, map[string]string{}},
+
+		{
+			map[string]string{"Clarity2012": "50"},
+			map[string]string{"lc_enabled": "true", "lc_strength": "30", "lc_radius": "40"},
+		},
+		{
+			map[string]string{"Clarity2012": "100"},
+			map[string]string{"lc_enabled": "true", "lc_strength": "60", "lc_radius": "50"},
+		},
+		{
+			map[string]string{"Dehaze": "100"},
+			map[string]string{"lc_enabled": "true", "lc_strength": "70", "lc_radius": "120"},
+		},
+
+		// Clarity and dehaze share the module: strengths add up (limited to 100),
+		// the radius is weighted by strength: (50 * 60 + 120 * 70) / 130
+		{
+			map[string]string{"Clarity2012": "100", "Dehaze": "100"},
+			map[string]string{"lc_enabled": "true", "lc_strength": "100", "lc_radius": "88"},
+		},
+
+		// Texture uses the wavelet sharpen USM in clarity mode
+		{
+			map[string]string{"Texture": "50"},
+			map[string]string{
+				"WaveletSharpen2.bSphWaveletUsmon":      "true",
+				"WaveletSharpen2.bSphWaveletUsmClarity": "true",
+				"WaveletSharpen2.bSphWaveletUsmRadius":  "7",
+				"WaveletSharpen2.bSphWaveletUsmAmount":  "40",
+			},
+		},
+		{
+			map[string]string{"Texture": "100", "Clarity2012": "20"},
+			map[string]string{
+				"WaveletSharpen2.bSphWaveletUsmon":      "true",
+				"WaveletSharpen2.bSphWaveletUsmClarity": "true",
+				"WaveletSharpen2.bSphWaveletUsmRadius":  "10",
+				"WaveletSharpen2.bSphWaveletUsmAmount":  "80",
+				"lc_enabled":                            "true",
+				"lc_strength":                           "12",
+				"lc_radius":                             "34",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		testConversionPass(t, convertLocalContrast, test.settings, test.expected)
+	}
+}
@@ -0,0 +1,71 @@
+package lib
+
+import (
+	"encoding/xml"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func readTestLightroomPreset(t *testing.T, path string) LightroomPreset {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lightroom := NewLightroomPreset()
+	err = xml.Unmarshal(contents, &lightroom)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return lightroom
+}
+
+func TestLayerOptionsRespectTheTarget(t *testing.T) {
+	lightroom := readTestLightroomPreset(t, filepath.Join("testdata", "preset.xmp"))
+
+	// Layers only use options of aftershot itself, which all versions have
+	for _, target := range AFTERSHOT_TARGETS {
+		options := NewConversionOptions()
+		options.Target = target
+		preset := NewAftershotPresetFromLightroomWithOptions(lightroom, options)
+		if len(preset.Layers) != 1 || !preset.Layers[0].Settings.Exposure.IsSet {
+			t.Errorf("%s: expected a layer with exposure, got %+v", target.Name, preset.Layers)
+		}
+	}
+
+	// A layer option of a plugin the target does not have is removed like any other option
+	newPreset := func(target string) AfterShotPreset {
+		preset := newTestAfterShotPreset()
+		preset.Target = AFTERSHOT_TARGETS[target]
+		layer := NewAfterShotLayer("Graduated Filter 1")
+		layer.Settings.Saturation.Name = "Equalizer_kb.kbs_redsat"
+		layer.Settings.Saturation.Set(-20)
+		preset.Layers = append(preset.Layers, layer)
+		return preset
+	}
+
+	if layers := removeUnsupportedOptions(lightroom, newPreset("asp3")).Layers; len(layers) != 1 || !layers[0].Settings.Saturation.IsSet {
+		t.Errorf("asp3: the layer option should be kept, got %+v", layers)
+	}
+
+	var layers []AfterShotLayer
+	output := captureLog(func() {
+		layers = removeUnsupportedOptions(lightroom, newPreset("asp2")).Layers
+	})
+	if len(layers) != 0 {
+		t.Errorf("asp2: expected the layer to be left out, got %+v", layers)
+	}
+	if !strings.Contains(output, "[WARN] Option 'Equalizer_kb.kbs_redsat' with value '-20' is not available in target asp2") ||
+		!strings.Contains(output, "[WARN] Graduated Filter 1 has no options left that are available in target asp2") {
+		t.Errorf("expected warnings about the option and the left out layer, got '%s'", output)
+	}
+
+	preset := newPreset("asp2")
+	preset.Layers[0].Settings.Exposure.Set(0.5)
+	layers = removeUnsupportedOptions(lightroom, preset).Layers
+	if len(layers) != 1 || layers[0].Settings.Saturation.IsSet || !layers[0].Settings.Exposure.IsSet {
+		t.Errorf("asp2: only the unsupported option should be removed, got %+v", layers)
+	}
+}
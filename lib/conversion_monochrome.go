@@ -0,0 +1,100 @@
+package lib
+
+import (
+	"log"
+	"math"
+)
+
+// Maximum shift of a channel curve at a toning saturation of 100, relative to the curve range
+const TONING_STRENGTH = 0.15
+
+// Shift of the shadow and highlight tones at a split toning balance of 100
+const TONING_BALANCE_SHIFT = 0.15
+
+// Lightroom ignores the HSL panel for black and white photos. Instead, the gray mixer decides
+// how bright every color becomes. The mixer is applied as luminance adjustments of the equalizer
+// before everything is desaturated.
+func convertMonochrome(lightroom LightroomPreset, preset AfterShotPreset) AfterShotPreset {
+	settings := lightroom.Settings
+	if !settings.ConvertToGrayscale.Value {
+		return preset
+	}
+
+	// The neutral saturation is 0, the photo is only free of color at the minimum
+	saturation := &preset.Settings.Basic.Saturation
+	saturation.Set(saturation.Min)
+
+	for aftershot := range AFTERSHOT_EQUALIZER_BAND_HUES {
+		value := 0.0
+		changed := false
+		for lightroom, band := range LIGHTROOM_HSL_BANDS {
+			weight := hslBandWeights[aftershot][lightroom]
+			if weight == 0 || !settings.IsChanged("GrayMixer"+band) {
+				continue
+			}
+
+			value += weight * settings.Value("GrayMixer"+band)
+			changed = true
+		}
+
+		if changed {
+			preset.Settings.Equalizer.Luminance[aftershot].Set(value)
+		}
+	}
+
+	return preset
+}
+
+// A tone of split toning / color grading, applied at the given position of the tone curve (0 - 1)
+type toningTone struct {
+	Position   float64
+	Hue        float64
+	Saturation float64
+}
+
+// Split toning and color grading of midtones are translated to the channel curves. Every
+// tone moves the channels towards its color at the position of the tone.
+func convertToning(lightroom LightroomPreset, preset AfterShotPreset) AfterShotPreset {
+	settings := lightroom.Settings
+	if !settings.IsChanged("SplitToningShadowSaturation") &&
+		!settings.IsChanged("SplitToningHighlightSaturation") &&
+		!settings.IsChanged("ColorGradeMidtoneSat") {
+		return preset
+	}
+
+	curves := &preset.Settings.Curves.ToneCurve
+	if !curves.Red.isIdentity() || !curves.Green.isIdentity() || !curves.Blue.isIdentity() {
+		log.Printf("[WARN] The preset uses channel curves, split toning cannot be added to them and will be ignored.")
+		return preset
+	}
+
+	// Positive balance gives more room to the highlight tone
+	balance := settings.Value("SplitToningBalance") / 100 * TONING_BALANCE_SHIFT
+	tones := []toningTone{
+		{0.25 - balance, settings.Value("SplitToningShadowHue"), settings.Value("SplitToningShadowSaturation")},
+		{0.5, settings.Value("ColorGradeMidtoneHue"), settings.Value("ColorGradeMidtoneSat")},
+		{0.75 - balance, settings.Value("SplitToningHighlightHue"), settings.Value("SplitToningHighlightSaturation")},
+	}
+
+	log.Printf("[INFO] Split toning is translated to the channel curves")
+	channels := []*AfterShotToneCurveChannel{&curves.Red, &curves.Green, &curves.Blue}
+	for channel, curve := range channels {
+		curve.Points = []AfterShotToneCurvePoint{{In: 0, Out: 0}}
+		for _, tone := range tones {
+			if tone.Saturation == 0 {
+				continue
+			}
+
+			color := hsvToRgb(tone.Hue, 1, 1)
+			offset := (color[channel] - (color[0]+color[1]+color[2])/3) * tone.Saturation / 100 * TONING_STRENGTH
+			position := tone.Position * AFTERSHOT_CURVE_MAX
+			curve.Points = append(curve.Points, AfterShotToneCurvePoint{
+				In:  int(math.Round(position)),
+				Out: int(math.Round(math.Max(0, math.Min(AFTERSHOT_CURVE_MAX, position+offset*AFTERSHOT_CURVE_MAX)))),
+			})
+		}
+		curve.Points = append(curve.Points, AfterShotToneCurvePoint{In: AFTERSHOT_CURVE_MAX, Out: AFTERSHOT_CURVE_MAX})
+	}
+
+	return preset
+}
@@ -0,0 +1,54 @@
+package lib
+
+import (
+	"testing"
+)
+
+func TestConvertMonochromeUsesTheGrayMixerPerBand(t *testing.T) {
+	lightroom := NewLightroomPreset()
+	lightroom.Set("ConvertToGrayscale", "True")
+	lightroom.Set("GrayMixerBlue", "-60")
+	lightroom.Set("GrayMixerOrange", "20")
+
+	preset := convertMonochrome(lightroom, newTestAfterShotPreset())
+	luminance := preset.Settings.Equalizer.Luminance
+	if luminance[5].Value != -60 || luminance[1].Value != 20 {
+		t.Errorf("blue = %v, orange = %v, expected -60 and 20", luminance[5].Value, luminance[1].Value)
+	}
+
+	saturation := preset.Settings.Basic.Saturation
+	if !saturation.IsSet || saturation.Value != -100 || saturation.IsNeutral() {
+		t.Errorf("black and white photos should be desaturated, saturation is %s", saturation.Serialize())
+	}
+}
+
+func TestConvertToningIgnoresLinearChannelCurves(t *testing.T) {
+	lightroom := NewLightroomPreset()
+	lightroom.Set("SplitToningShadowHue", "220")
+	lightroom.Set("SplitToningShadowSaturation", "30")
+
+	preset := newTestAfterShotPreset()
+	curves := &preset.Settings.Curves.ToneCurve
+	linear := []AfterShotToneCurvePoint{{0, 0}, {65535, 65535}}
+	curves.Red = newAfterShotToneCurveChannel(linear)
+	curves.Green = newAfterShotToneCurveChannel(linear)
+	curves.Blue = newAfterShotToneCurveChannel(linear)
+
+	preset = convertToning(lightroom, preset)
+	blue := preset.Settings.Curves.ToneCurve.Blue.Points
+	if len(blue) != 3 || blue[1].Out <= blue[1].In {
+		t.Errorf("a blue shadow tone should raise the blue shadows, got %+v", blue)
+	}
+	red := preset.Settings.Curves.ToneCurve.Red.Points
+	if len(red) != 3 || red[1].Out >= red[1].In {
+		t.Errorf("a blue shadow tone should lower the red shadows, got %+v", red)
+	}
+
+	// Real channel curves are kept
+	preset = newTestAfterShotPreset()
+	preset.Settings.Curves.ToneCurve.Red = newAfterShotToneCurveChannel([]AfterShotToneCurvePoint{{0, 0}, {32768, 36000}, {65535, 65535}})
+	preset = convertToning(lightroom, preset)
+	if len(preset.Settings.Curves.ToneCurve.Blue.Points) != 0 {
+		t.Errorf("toning should be skipped for presets with channel curves")
+	}
+}
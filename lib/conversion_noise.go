@@ -0,0 +1,50 @@
+package lib
+
+import (
+	"log"
+)
+
+// Maps lightroom luminance and color noise reduction onto the noise removal module of the target.
+// Lightroom and noise ninja both use a range of 0 - 100 for strength and detail, so values are
+// copied directly.
+func convertNoiseReduction(lightroom LightroomPreset, preset AfterShotPreset) AfterShotPreset {
+	settings := lightroom.Settings
+	luminance := settings.IsChanged("LuminanceSmoothing") ||
+		settings.IsChanged("LuminanceNoiseReductionDetail") ||
+		settings.IsChanged("LuminanceNoiseReductionContrast")
+	color := settings.IsChanged("ColorNoiseReduction") ||
+		settings.IsChanged("ColorNoiseReductionDetail") ||
+		settings.IsChanged("ColorNoiseReductionSmoothness")
+
+	if !luminance && !color {
+		return preset
+	}
+
+	if preset.Target.NoiseModule == AFTERSHOT_NOISE_MODULE_NONE {
+		log.Printf("[WARN] This preset uses noise reduction. Target %s has no noise removal, noise reduction will be ignored.", preset.Target.Name)
+		return preset
+	}
+
+	log.Printf("[INFO] Noise reduction is translated to the %s module of target %s", preset.Target.NoiseModule, preset.Target.Name)
+	noise := &preset.Settings.Noise
+
+	if luminance {
+		noise.LuminanceStrength.Set(settings.Value("LuminanceSmoothing"))
+		noise.LuminanceDetail.Set(settings.Value("LuminanceNoiseReductionDetail"))
+		noise.LuminanceContrast.Set(settings.Value("LuminanceNoiseReductionContrast"))
+	}
+
+	if color {
+		noise.ChromaStrength.Set(settings.Value("ColorNoiseReduction"))
+		noise.ChromaDetail.Set(settings.Value("ColorNoiseReductionDetail"))
+
+		if settings.IsChanged("ColorNoiseReductionSmoothness") {
+			log.Printf("[WARN] Noise ninja has no equivalent for ColorNoiseReductionSmoothness, it will be ignored.")
+		}
+	}
+
+	// Noise ninja is also used to turn noise reduction of the raw defaults off
+	noise.Enabled.SetBool(settings.Value("LuminanceSmoothing") > 0 || settings.Value("ColorNoiseReduction") > 0)
+
+	return preset
+}
@@ -0,0 +1,74 @@
+package lib
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestConvertNoiseReduction(t *testing.T) {
+	tests := []struct {
+		settings map[string]string
+		expected map[string]string
+	}{
+		// The lightroom defaults leave noise ninja alone
+		{map[string]string{"LuminanceSmoothing": "0", "ColorNoiseReduction": "25"}, map[string]string{}},
+
+		{
+			map[string]string{"LuminanceSmoothing": "40"},
+			map[string]string{
+				"nn_enabled":      "true",
+				"nn_lumastrength": "40",
+				"nn_lumadetail":   "50",
+				"nn_lumacontrast": "0",
+			},
+		},
+		{
+			map[string]string{"LuminanceSmoothing": "100", "LuminanceNoiseReductionDetail": "100", "LuminanceNoiseReductionContrast": "100"},
+			map[string]string{
+				"nn_enabled":      "true",
+				"nn_lumastrength": "100",
+				"nn_lumadetail":   "100",
+				"nn_lumacontrast": "100",
+			},
+		},
+		{
+			map[string]string{"ColorNoiseReduction": "100", "ColorNoiseReductionDetail": "0"},
+			map[string]string{
+				"nn_enabled":        "true",
+				"nn_chromastrength": "100",
+				"nn_chromadetail":   "0",
+			},
+		},
+
+		// Turning all noise reduction off also turns off the noise reduction of the raw defaults
+		{
+			map[string]string{"ColorNoiseReduction": "0"},
+			map[string]string{
+				"nn_enabled":        "false",
+				"nn_chromastrength": "0",
+				"nn_chromadetail":   "50",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		testConversionPass(t, convertNoiseReduction, test.settings, test.expected)
+	}
+}
+
+func TestConvertNoiseReductionWithoutNoiseModule(t *testing.T) {
+	lightroom := NewLightroomPreset()
+	lightroom.Set("LuminanceSmoothing", "40")
+	preset := newTestAfterShotPreset()
+	preset.Target = AFTERSHOT_TARGETS["as3"]
+
+	output := captureLog(func() {
+		preset = convertNoiseReduction(lightroom, preset)
+	})
+	if options := setOptions(preset.Settings); len(options) != 0 {
+		t.Errorf("as3 has no noise removal, expected no options but got %v", options)
+	}
+	if !strings.Contains(output, "[WARN] This preset uses noise reduction. Target as3 has no noise removal") {
+		t.Errorf("expected a warning about the missing noise removal, got '%s'", output)
+	}
+}
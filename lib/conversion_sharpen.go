@@ -0,0 +1,102 @@
+package lib
+
+import (
+	"log"
+	"math"
+)
+
+const SHARPEN_MODULE_CORE = "core sharpen"
+const SHARPEN_MODULE_WAVELET_SHARPEN = "wavelet sharpen"
+
+// Neither tool documents how its sharpening relates to the other. The core sharpen radius, the
+// default wavelet sharpen amount and the detail and masking factors below are not calibrated,
+// they were estimated by comparing sharpened photos visually.
+
+// Radius in pixels that the core sharpening of aftershot works at. Lightroom radii that differ
+// more than SHARPEN_RADIUS_TOLERANCE from it are converted to the wavelet sharpen USM instead.
+const AFTERSHOT_CORE_SHARPEN_RADIUS = 1.0
+const SHARPEN_RADIUS_TOLERANCE = 0.3
+
+// The amount, radius, detail and masking of lightroom's sharpening combined into the
+// parameters of one of the aftershot sharpening modules.
+type sharpening struct {
+	Module string
+	Amount float64
+	Radius float64
+}
+
+// Wavelet sharpen USM amount that lightroom's default sharpening is translated to. The USM has no
+// neutral value, so this is the amount of a comparable strength.
+const AFTERSHOT_WAVELET_SHARPEN_DEFAULT_AMOUNT = 20
+
+// Detail suppresses halos at low values and emphasizes fine texture at high values.
+// Neither aftershot module has a halo control, so detail is folded into the amount, which
+// is neutral at lightroom's default of 25.
+func sharpenDetailFactor(detail float64) float64 {
+	return 1 + (detail-25)/100
+}
+
+// Masking restricts sharpening to edges. The flat areas it protects make up most of the image,
+// so the amount is reduced instead (by up to half at a masking of 100).
+func sharpenMaskingFactor(masking float64) float64 {
+	return 1 - masking/200
+}
+
+// Chooses the aftershot module and its parameters for lightroom's sharpening. The amount is
+// relative to lightroom's default sharpening (which depends on the process version), so that
+// the default equals coreNeutral of the core sharpen module or AFTERSHOT_WAVELET_SHARPEN_DEFAULT_AMOUNT
+// of the wavelet sharpen USM.
+func newSharpening(settings LightroomDevelopSettings, waveletAvailable bool, coreNeutral float64) sharpening {
+	amount := settings.Value("Sharpness") / settings.Default("Sharpness") *
+		sharpenDetailFactor(settings.Value("SharpenDetail")) *
+		sharpenMaskingFactor(settings.Value("SharpenEdgeMasking"))
+	radius := settings.Value("SharpenRadius")
+
+	if waveletAvailable && math.Abs(radius-AFTERSHOT_CORE_SHARPEN_RADIUS) > SHARPEN_RADIUS_TOLERANCE {
+		return sharpening{Module: SHARPEN_MODULE_WAVELET_SHARPEN, Amount: amount * AFTERSHOT_WAVELET_SHARPEN_DEFAULT_AMOUNT, Radius: radius}
+	}
+
+	return sharpening{Module: SHARPEN_MODULE_CORE, Amount: amount * coreNeutral, Radius: AFTERSHOT_CORE_SHARPEN_RADIUS}
+}
+
+// Maps Sharpness, SharpenRadius, SharpenDetail and SharpenEdgeMasking together. Must run after the
+// local contrast pass: if Texture uses wavelet sharpen, sharpening falls back to the core module.
+func convertSharpening(lightroom LightroomPreset, preset AfterShotPreset) AfterShotPreset {
+	settings := lightroom.Settings
+	if !settings.IsChanged("Sharpness") && !settings.IsChanged("SharpenRadius") &&
+		!settings.IsChanged("SharpenDetail") && !settings.IsChanged("SharpenEdgeMasking") {
+		return preset
+	}
+
+	wavelet := &preset.Settings.WaveletSharpen
+	waveletAvailable := preset.Target.SupportsOption(wavelet.UsmEnabled.Name)
+	if waveletAvailable && wavelet.UsmEnabled.IsSet && wavelet.UsmEnabled.Value != 0 {
+		log.Printf("[INFO] The wavelet sharpen plugin is already used for Texture, sharpening uses the core sharpen module instead.")
+		waveletAvailable = false
+	}
+
+	if settings.IsChanged("SharpenEdgeMasking") {
+		log.Printf("[INFO] Aftershot cannot mask sharpening to edges, SharpenEdgeMasking reduces the amount of sharpening instead.")
+	}
+
+	result := newSharpening(settings, waveletAvailable, preset.Settings.Basic.Sharpen.Neutral)
+	switch result.Module {
+	case SHARPEN_MODULE_WAVELET_SHARPEN:
+		log.Printf("[INFO] Sharpening with a radius of %s is translated to usage of the wavelet sharpen plugin. Make sure you have that plugin installed", formatNumber(result.Radius))
+
+		// The core module would sharpen a second time
+		preset.Settings.Basic.Sharpen.Set(0)
+		wavelet.UsmEnabled.SetBool(true)
+		wavelet.UsmClarity.SetBool(false)
+		wavelet.UsmRadius.Set(result.Radius)
+		wavelet.UsmAmount.Set(math.Min(result.Amount, 100))
+
+	case SHARPEN_MODULE_CORE:
+		if math.Abs(settings.Value("SharpenRadius")-AFTERSHOT_CORE_SHARPEN_RADIUS) > SHARPEN_RADIUS_TOLERANCE {
+			log.Printf("[WARN] The core sharpen module of aftershot has a fixed radius, SharpenRadius (%s) will be ignored.", formatNumber(settings.Value("SharpenRadius")))
+		}
+		preset.Settings.Basic.Sharpen.Set(math.Min(result.Amount, 200))
+	}
+
+	return preset
+}
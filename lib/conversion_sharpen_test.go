@@ -0,0 +1,46 @@
+package lib
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDefaultSharpeningIsNeutral(t *testing.T) {
+	tests := []struct {
+		processVersion string
+		sharpness      string
+	}{
+		{"10.0", "40"},
+		{"5.7", "25"},
+		{"5.0", "25"},
+	}
+
+	for _, test := range tests {
+		lightroom := NewLightroomPreset()
+		lightroom.Set("ProcessVersion", test.processVersion)
+		lightroom.Set("Sharpness", test.sharpness)
+
+		result := newSharpening(lightroom.Settings, false, 100)
+		if result.Module != SHARPEN_MODULE_CORE || math.Abs(result.Amount-100) > 1e-9 {
+			t.Errorf("process version %s: default sharpness %s converted to %s %v, expected core sharpen 100", test.processVersion, test.sharpness, result.Module, result.Amount)
+		}
+	}
+}
+
+func TestSharpeningIsRelativeToTheDefault(t *testing.T) {
+	lightroom := NewLightroomPreset()
+	lightroom.Set("ProcessVersion", "10.0")
+	lightroom.Set("Sharpness", "80")
+	lightroom.Set("SharpenRadius", "1")
+
+	preset := convertSharpening(lightroom, newTestAfterShotPreset())
+	if sharpen := preset.Settings.Basic.Sharpen; !sharpen.IsSet || sharpen.Value != 200 {
+		t.Errorf("twice the default sharpness should be converted to 200, got %v", sharpen.Value)
+	}
+
+	lightroom.Set("SharpenRadius", "2")
+	result := newSharpening(lightroom.Settings, true, 100)
+	if result.Module != SHARPEN_MODULE_WAVELET_SHARPEN || math.Abs(result.Amount-2*AFTERSHOT_WAVELET_SHARPEN_DEFAULT_AMOUNT) > 1e-9 {
+		t.Errorf("expected a wavelet sharpen amount of %v, got %s %v", 2*AFTERSHOT_WAVELET_SHARPEN_DEFAULT_AMOUNT, result.Module, result.Amount)
+	}
+}
@@ -0,0 +1,127 @@
+package lib
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// An empty preset for the default target, as the conversion passes get it
+func newTestAfterShotPreset() AfterShotPreset {
+	return AfterShotPreset{
+		Target:   AFTERSHOT_TARGETS[AFTERSHOT_DEFAULT_TARGET],
+		Settings: NewAfterShotSettings(),
+	}
+}
+
+// Serialized values of the options that are set, by option name
+func setOptions(settings AfterShotSettings) map[string]string {
+	values := map[string]string{}
+	for _, option := range settings.Options() {
+		if option.IsSet {
+			values[option.Name] = option.Serialize()
+		}
+	}
+	return values
+}
+
+// Converts the given lightroom settings with a single pass and compares the options it sets
+func testConversionPass(t *testing.T, pass func(LightroomPreset, AfterShotPreset) AfterShotPreset, settings map[string]string, expected map[string]string) {
+	t.Helper()
+
+	lightroom := NewLightroomPreset()
+	for name, value := range settings {
+		lightroom.Set(name, value)
+	}
+
+	actual := setOptions(pass(lightroom, newTestAfterShotPreset()).Settings)
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("%v: set options %v, expected %v", settings, actual, expected)
+	}
+}
+
+func TestNewAfterShotToneCurvePointFromLightroomToneCurvePoint(t *testing.T) {
+	tests := []struct {
+		lightroom float64
+		expected  int
+	}{
+		{0, 0},
+		{0.5, 129},
+		{1, 257},
+		{127.5, 32768},
+		{128, 32896},
+		{254.5, 65407},
+		{255, 65535},
+		{-1, 0},
+		{256, 65535},
+	}
+
+	for _, test := range tests {
+		point := newAfterShotToneCurvePointFromLightroomToneCurvePoint(LightroomToneCurvePoint{In: test.lightroom, Out: test.lightroom})
+		if point.In != test.expected || point.Out != test.expected {
+			t.Errorf("%v is converted to %+v, expected %d", test.lightroom, point, test.expected)
+		}
+	}
+}
+
+func TestConvertedToneCurveAtMidtone(t *testing.T) {
+	tests := []struct {
+		points   []LightroomToneCurvePoint
+		expected float64
+	}{
+		{[]LightroomToneCurvePoint{{0, 0}, {255, 255}}, 32768},
+		{[]LightroomToneCurvePoint{{0, 0}, {127.5, 140}, {255, 255}}, 35980},
+		{[]LightroomToneCurvePoint{{0, 0}, {64, 50}, {127.5, 127.5}, {192, 205}, {255, 255}}, 32768},
+	}
+
+	for _, test := range tests {
+		channel := newAfterShotToneCurveChannelFromLightRoomToneCurveChannel(LightroomToneCurve{Points: test.points})
+		points := make([][2]float64, len(channel.Points))
+		for index, point := range channel.Points {
+			points[index] = [2]float64{float64(point.In), float64(point.Out)}
+		}
+
+		midtone := newAfterShotCurveInterpolation(points)(32768)
+		if midtone < test.expected-1 || midtone > test.expected+1 {
+			t.Errorf("curve %v is %v at the midtone, expected %v", test.points, midtone, test.expected)
+		}
+	}
+}
+
+// A reset preset has to overwrite everything a previous edit may have changed
+func TestResetPresetContainsEveryNeutralOption(t *testing.T) {
+	options := NewConversionOptions()
+	options.Mode = PRESET_MODE_RESET
+	preset := NewAftershotPresetFromLightroomWithOptions(NewLightroomPreset(), options)
+
+	output := bytes.Buffer{}
+	_, err := preset.WriteTo(&output)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The conversion enables the curves and the equalizer, which are neutral as long as they are unchanged
+	enabled := map[string]bool{
+		preset.Settings.Curves.Enabled.Name:    true,
+		preset.Settings.Equalizer.Enabled.Name: true,
+	}
+
+	neutral := NewAfterShotSettings()
+	for _, option := range neutral.Options() {
+		if !options.Target.SupportsOption(option.Name) {
+			continue
+		}
+
+		option.Reset()
+		if enabled[option.Name] {
+			option.SetBool(true)
+		}
+
+		attribute := fmt.Sprintf(`bopt:%s="%s"`, option.Name, option.Serialize())
+		if !strings.Contains(output.String(), attribute) {
+			t.Errorf("the reset preset does not contain %s", attribute)
+		}
+	}
+}
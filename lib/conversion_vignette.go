@@ -0,0 +1,78 @@
+package lib
+
+import (
+	"log"
+)
+
+const LIGHTROOM_VIGNETTE_STYLE_HIGHLIGHT_PRIORITY = 1
+const LIGHTROOM_VIGNETTE_STYLE_COLOR_PRIORITY = 2
+const LIGHTROOM_VIGNETTE_STYLE_PAINT_OVERLAY = 3
+
+// Lightroom's midpoint is the position of the center of the falloff, from the center (0)
+// to the corners (100). Aftershot's radius is the inner edge of the falloff in percent of the
+// half diagonal. The points are approximated for the default feather of both tools.
+var vignetteMidpointToRadius = [][2]float64{
+	{0, 5},
+	{25, 25},
+	{50, 45},
+	{75, 62},
+	{100, 80},
+}
+
+// Interpolates linearly between the given points, which must be sorted by x.
+// Values outside of the points are clamped.
+func interpolateLinear(points [][2]float64, x float64) float64 {
+	if x <= points[0][0] {
+		return points[0][1]
+	}
+
+	for index := 1; index < len(points); index++ {
+		if x <= points[index][0] {
+			start := points[index-1]
+			end := points[index]
+			return start[1] + (end[1]-start[1])*(x-start[0])/(end[0]-start[0])
+		}
+	}
+
+	return points[len(points)-1][1]
+}
+
+// Maps the post crop vignette and the lens vignetting of lightroom.
+// Presets do not contain a crop, so the post crop vignette applies to the full image.
+func convertVignette(lightroom LightroomPreset, preset AfterShotPreset) AfterShotPreset {
+	settings := lightroom.Settings
+
+	if settings.IsChanged("PostCropVignetteAmount") {
+		vignette := &preset.Settings.Vignette
+		vignette.Enabled.SetBool(true)
+		vignette.Amount.Set(settings.Value("PostCropVignetteAmount"))
+		vignette.Roundness.Set(settings.Value("PostCropVignetteRoundness"))
+
+		// Lightroom places the midpoint in the middle of the feathered area, aftershot starts the
+		// feathered area at the radius. Feathering in lightroom is relative to the distance between
+		// the midpoint and the corners which means a wider feather moves the start inwards.
+		midpoint := settings.Value("PostCropVignetteMidpoint")
+		feather := settings.Value("PostCropVignetteFeather")
+		radius := interpolateLinear(vignetteMidpointToRadius, midpoint)
+		radius -= (feather - 50) * (100 - radius) / 200
+		vignette.Radius.Set(radius)
+		vignette.Feather.Set(feather)
+
+		switch int(settings.Value("PostCropVignetteStyle")) {
+		case LIGHTROOM_VIGNETTE_STYLE_HIGHLIGHT_PRIORITY:
+			vignette.HighlightProtection.Set(settings.Value("PostCropVignetteHighlightContrast"))
+		case LIGHTROOM_VIGNETTE_STYLE_COLOR_PRIORITY:
+			// Color priority keeps hues in the darkened area, aftershot always does that
+		case LIGHTROOM_VIGNETTE_STYLE_PAINT_OVERLAY:
+			log.Printf("[WARN] Aftershot has no paint overlay vignettes, the vignette is converted to a regular vignette which will look less washed out.")
+		}
+	}
+
+	if settings.IsChanged("VignetteAmount") {
+		preset.Settings.Lens.Enabled.SetBool(true)
+		preset.Settings.Lens.VignetteAmount.Set(settings.Value("VignetteAmount"))
+		preset.Settings.Lens.VignetteMidpoint.Set(settings.Value("VignetteMidpoint"))
+	}
+
+	return preset
+}
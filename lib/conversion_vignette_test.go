@@ -0,0 +1,115 @@
+package lib
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestConvertVignette(t *testing.T) {
+	tests := []struct {
+		settings map[string]string
+		expected map[string]string
+	}{
+		{map[string]string{"PostCropVignetteAmount": "0", "VignetteAmount": "0"}, map[string]string{}},
+
+		// Default midpoint and feather
+		{
+			map[string]string{"PostCropVignetteAmount": "-30"},
+			map[string]string{
+				"vig_enabled":    "true",
+				"vig_amount":     "-30",
+				"vig_roundness":  "0",
+				"vig_radius":     "45",
+				"vig_feather":    "50",
+				"vig_highlights": "0",
+			},
+		},
+
+		// No feather starts the falloff further out: 5 + 50 * 95 / 200
+		{
+			map[string]string{"PostCropVignetteAmount": "-100", "PostCropVignetteMidpoint": "0", "PostCropVignetteFeather": "0", "PostCropVignetteRoundness": "-100"},
+			map[string]string{
+				"vig_enabled":    "true",
+				"vig_amount":     "-100",
+				"vig_roundness":  "-100",
+				"vig_radius":     "29",
+				"vig_feather":    "0",
+				"vig_highlights": "0",
+			},
+		},
+
+		// Full feather starts it further in: 80 - 50 * 20 / 200
+		{
+			map[string]string{
+				"PostCropVignetteAmount":            "100",
+				"PostCropVignetteMidpoint":          "100",
+				"PostCropVignetteFeather":           "100",
+				"PostCropVignetteRoundness":         "100",
+				"PostCropVignetteHighlightContrast": "100",
+			},
+			map[string]string{
+				"vig_enabled":    "true",
+				"vig_amount":     "100",
+				"vig_roundness":  "100",
+				"vig_radius":     "75",
+				"vig_feather":    "100",
+				"vig_highlights": "100",
+			},
+		},
+
+		// Midpoints between the points are interpolated: 45 + 17 * 10 / 25.
+		// Color priority has no highlight protection.
+		{
+			map[string]string{"PostCropVignetteAmount": "-50", "PostCropVignetteMidpoint": "60", "PostCropVignetteStyle": "2"},
+			map[string]string{
+				"vig_enabled":   "true",
+				"vig_amount":    "-50",
+				"vig_roundness": "0",
+				"vig_radius":    "52",
+				"vig_feather":   "50",
+			},
+		},
+
+		// Lens vignetting
+		{
+			map[string]string{"VignetteAmount": "-100"},
+			map[string]string{
+				"lens_enabled":     "true",
+				"lens_vigamount":   "-100",
+				"lens_vigmidpoint": "50",
+			},
+		},
+		{
+			map[string]string{"VignetteAmount": "100", "VignetteMidpoint": "0"},
+			map[string]string{
+				"lens_enabled":     "true",
+				"lens_vigamount":   "100",
+				"lens_vigmidpoint": "0",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		testConversionPass(t, convertVignette, test.settings, test.expected)
+	}
+}
+
+func TestConvertPaintOverlayVignette(t *testing.T) {
+	output := captureLog(func() {
+		testConversionPass(
+			t,
+			convertVignette,
+			map[string]string{"PostCropVignetteAmount": "40", "PostCropVignetteStyle": "3"},
+			map[string]string{
+				"vig_enabled":   "true",
+				"vig_amount":    "40",
+				"vig_roundness": "0",
+				"vig_radius":    "45",
+				"vig_feather":   "50",
+			},
+		)
+	})
+	if !strings.Contains(output, "[WARN] Aftershot has no paint overlay vignettes") {
+		t.Errorf("expected a warning about the paint overlay, got '%s'", output)
+	}
+}
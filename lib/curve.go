@@ -0,0 +1,180 @@
+package lib
+
+import (
+	"math"
+	"sort"
+)
+
+// A curve through control points, evaluated at any input value. Inputs outside of the control
+// points keep the value of the first / last point, like both applications do.
+type curveInterpolation func(x float64) float64
+
+// Sorts the points by input and removes points with the same input, the last one wins.
+func normalizeCurvePoints(points [][2]float64) [][2]float64 {
+	sorted := make([][2]float64, len(points))
+	copy(sorted, points)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i][0] < sorted[j][0]
+	})
+
+	unique := [][2]float64{}
+	for _, point := range sorted {
+		if len(unique) > 0 && unique[len(unique)-1][0] == point[0] {
+			unique[len(unique)-1] = point
+			continue
+		}
+		unique = append(unique, point)
+	}
+	return unique
+}
+
+// Finds the segment of the points that contains x, the points must be sorted and there must be at least 2.
+func curveSegment(points [][2]float64, x float64) int {
+	segment := sort.Search(len(points), func(index int) bool {
+		return points[index][0] > x
+	}) - 1
+	return int(math.Max(0, math.Min(float64(segment), float64(len(points)-2))))
+}
+
+// Lightroom interpolates its tone curves with a natural cubic spline, which overshoots between
+// points that are close to each other. The result is clamped to the range of the curve.
+func newLightroomCurveInterpolation(points [][2]float64, max float64) curveInterpolation {
+	points = normalizeCurvePoints(points)
+	count := len(points)
+	if count < 2 {
+		return func(x float64) float64 { return x }
+	}
+
+	// Second derivatives at every point, solved with the tridiagonal algorithm.
+	// Natural splines have no curvature at both ends.
+	derivatives := make([]float64, count)
+	upper := make([]float64, count)
+	for index := 1; index < count-1; index++ {
+		previous := points[index][0] - points[index-1][0]
+		next := points[index+1][0] - points[index][0]
+		slopes := (points[index+1][1]-points[index][1])/next - (points[index][1]-points[index-1][1])/previous
+
+		diagonal := 2*(previous+next) - previous*upper[index-1]
+		upper[index] = next / diagonal
+		derivatives[index] = (6*slopes - previous*derivatives[index-1]) / diagonal
+	}
+	for index := count - 2; index > 0; index-- {
+		derivatives[index] -= upper[index] * derivatives[index+1]
+	}
+
+	return func(x float64) float64 {
+		x = math.Max(points[0][0], math.Min(x, points[count-1][0]))
+		segment := curveSegment(points, x)
+		start := points[segment]
+		end := points[segment+1]
+		width := end[0] - start[0]
+		a := (end[0] - x) / width
+		b := (x - start[0]) / width
+
+		y := a*start[1] + b*end[1] +
+			((a*a*a-a)*derivatives[segment]+(b*b*b-b)*derivatives[segment+1])*width*width/6
+		return math.Max(0, math.Min(y, max))
+	}
+}
+
+// Aftershot's curves do not seem to overshoot between points. Its interpolation is not documented,
+// a monotone cubic spline (Fritsch-Carlson) is assumed as it behaves like that.
+func newAfterShotCurveInterpolation(points [][2]float64) curveInterpolation {
+	points = normalizeCurvePoints(points)
+	count := len(points)
+	if count < 2 {
+		return func(x float64) float64 { return x }
+	}
+
+	secants := make([]float64, count-1)
+	for index := range secants {
+		secants[index] = (points[index+1][1] - points[index][1]) / (points[index+1][0] - points[index][0])
+	}
+
+	tangents := make([]float64, count)
+	tangents[0] = secants[0]
+	tangents[count-1] = secants[count-2]
+	for index := 1; index < count-1; index++ {
+		if secants[index-1]*secants[index] > 0 {
+			tangents[index] = (secants[index-1] + secants[index]) / 2
+		}
+	}
+
+	// Limits the tangents so that the curve stays monotone in every segment
+	for index, secant := range secants {
+		if secant == 0 {
+			tangents[index] = 0
+			tangents[index+1] = 0
+			continue
+		}
+
+		alpha := tangents[index] / secant
+		beta := tangents[index+1] / secant
+		length := math.Hypot(alpha, beta)
+		if length > 3 {
+			tangents[index] = 3 / length * alpha * secant
+			tangents[index+1] = 3 / length * beta * secant
+		}
+	}
+
+	return func(x float64) float64 {
+		x = math.Max(points[0][0], math.Min(x, points[count-1][0]))
+		segment := curveSegment(points, x)
+		start := points[segment]
+		end := points[segment+1]
+		width := end[0] - start[0]
+		t := (x - start[0]) / width
+
+		return (2*t*t*t-3*t*t+1)*start[1] +
+			(t*t*t-2*t*t+t)*width*tangents[segment] +
+			(-2*t*t*t+3*t*t)*end[1] +
+			(t*t*t-t*t)*width*tangents[segment+1]
+	}
+}
+
+// Chooses up to maxPoints control points for an aftershot curve so that it follows the given
+// curve between the first and the last of the initial points. Starts with the initial points
+// (or only their ends, if there are too many) and adds the point of the largest difference until
+// the difference is below the tolerance. The difference is checked at every integer input
+// (every 16 bit level for aftershot curves). Returns the points and the remaining difference.
+func resampleCurve(curve curveInterpolation, initial [][2]float64, maxPoints int, tolerance float64) ([][2]float64, float64) {
+	initial = normalizeCurvePoints(initial)
+	if len(initial) < 2 {
+		return initial, 0
+	}
+
+	first := initial[0][0]
+	last := initial[len(initial)-1][0]
+	points := initial
+	if len(points) > maxPoints {
+		points = [][2]float64{{first, curve(first)}, {last, curve(last)}}
+	}
+
+	for {
+		resampled := newAfterShotCurveInterpolation(points)
+		largest := 0.0
+		largestAt := first
+		for x := first; x <= last; x = math.Min(math.Floor(x)+1, last) {
+			difference := math.Abs(resampled(x) - curve(x))
+			if difference > largest {
+				largest = difference
+				largestAt = x
+			}
+			if x == last {
+				break
+			}
+		}
+
+		if largest <= tolerance || len(points) >= maxPoints {
+			return points, largest
+		}
+
+		// The largest difference can be at a point that already exists (e.g. if the curve differs
+		// from its own control points), adding it again would not change anything.
+		added := normalizeCurvePoints(append(points, [2]float64{largestAt, curve(largestAt)}))
+		if len(added) == len(points) {
+			return points, largest
+		}
+		points = added
+	}
+}
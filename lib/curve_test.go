@@ -0,0 +1,78 @@
+package lib
+
+import (
+	"math"
+	"testing"
+)
+
+func scaledCurvePoints(points [][2]float64) [][2]float64 {
+	scaled := make([][2]float64, len(points))
+	for index, point := range points {
+		scaled[index] = [2]float64{scaleLightroomCurveValue(point[0]), scaleLightroomCurveValue(point[1])}
+	}
+	return scaled
+}
+
+// Largest difference between both curves at any 16 bit level
+func largestCurveDifference(expected curveInterpolation, actual curveInterpolation) float64 {
+	largest := 0.0
+	for x := 0; x <= AFTERSHOT_CURVE_MAX; x++ {
+		largest = math.Max(largest, math.Abs(expected(float64(x))-actual(float64(x))))
+	}
+	return largest
+}
+
+func TestResampleCurveFollowsAnSCurve(t *testing.T) {
+	initial := scaledCurvePoints([][2]float64{{0, 0}, {48, 30}, {128, 128}, {200, 225}, {255, 255}})
+	lightroom := newLightroomCurveInterpolation(initial, AFTERSHOT_CURVE_MAX)
+
+	// Without resampling, the different interpolations differ by more than the tolerance
+	if largestCurveDifference(lightroom, newAfterShotCurveInterpolation(initial)) <= CURVE_RESAMPLING_TOLERANCE {
+		t.Fatalf("the S-curve should need resampling")
+	}
+
+	points, difference := resampleCurve(lightroom, initial, AFTERSHOT_NUM_POINTS, CURVE_RESAMPLING_TOLERANCE)
+	if len(points) > AFTERSHOT_NUM_POINTS {
+		t.Errorf("resampled curve has %d points, only %d are allowed", len(points), AFTERSHOT_NUM_POINTS)
+	}
+	if difference > CURVE_RESAMPLING_TOLERANCE {
+		t.Errorf("resampled curve differs by %v, expected at most %v", difference, CURVE_RESAMPLING_TOLERANCE)
+	}
+
+	actual := largestCurveDifference(lightroom, newAfterShotCurveInterpolation(points))
+	if actual > CURVE_RESAMPLING_TOLERANCE || actual != difference {
+		t.Errorf("resampled curve differs by %v at a 16 bit level, reported %v", actual, difference)
+	}
+}
+
+func TestResampleCurveStopsAtMaxPoints(t *testing.T) {
+	// A saw tooth cannot be followed with 4 points
+	initial := [][2]float64{{0, 0}, {65535, 65535}}
+	sawTooth := func(x float64) float64 {
+		return math.Mod(x, 8192) * 8
+	}
+
+	points, difference := resampleCurve(sawTooth, initial, 4, CURVE_RESAMPLING_TOLERANCE)
+	if len(points) > 4 {
+		t.Errorf("resampled curve has %d points, only 4 are allowed", len(points))
+	}
+	if difference <= CURVE_RESAMPLING_TOLERANCE {
+		t.Errorf("a saw tooth should not be representable with 4 points")
+	}
+}
+
+func TestResampleCurveStopsIfNoPointCanBeAdded(t *testing.T) {
+	// The curve differs the most at a point that already exists and cannot be moved there
+	initial := [][2]float64{{0, 0}, {1, 1}}
+	jump := func(x float64) float64 {
+		if x == 0 {
+			return 100
+		}
+		return x
+	}
+
+	points, _ := resampleCurve(jump, initial, AFTERSHOT_NUM_POINTS, 0)
+	if len(points) > AFTERSHOT_NUM_POINTS {
+		t.Errorf("resampled curve has %d points", len(points))
+	}
+}
@@ -0,0 +1,86 @@
+package lib
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"math/rand"
+)
+
+const GRAIN_TEXTURE_SIZE = 512
+
+// Standard deviation of the texture at lightroom's maximum grain amount, in 8 bit levels
+const GRAIN_MAX_DEVIATION = 48
+
+// Generates a grain texture around middle gray which can be used as an overlay layer.
+// The texture wraps around at its edges so it can be tiled without seams.
+// Parameters use the lightroom ranges of 0 - 100. The same parameters always produce
+// the same texture.
+func NewGrainTexture(amount float64, size float64, roughness float64) *image.Gray {
+	random := rand.New(rand.NewSource(1))
+	noise := make([]float64, GRAIN_TEXTURE_SIZE*GRAIN_TEXTURE_SIZE)
+	for index := range noise {
+		noise[index] = random.NormFloat64()
+	}
+
+	// Larger grain is white noise blurred with a larger radius. Blurring twice gets the
+	// grain closer to the round shape of film grain.
+	radius := 1 + int(size/20)
+	grain := wrappingBoxBlur(wrappingBoxBlur(noise, radius), radius)
+	normalizeDeviation(grain)
+
+	// Rough grain contains more fine noise in between the grains
+	mix := roughness / 100
+	for index := range grain {
+		grain[index] = (1-mix)*grain[index] + mix*noise[index]
+	}
+	normalizeDeviation(grain)
+
+	deviation := amount / 100 * GRAIN_MAX_DEVIATION
+	texture := image.NewGray(image.Rect(0, 0, GRAIN_TEXTURE_SIZE, GRAIN_TEXTURE_SIZE))
+	for index, value := range grain {
+		level := math.Max(0, math.Min(255, math.Round(128+value*deviation)))
+		texture.SetGray(index%GRAIN_TEXTURE_SIZE, index/GRAIN_TEXTURE_SIZE, color.Gray{Y: uint8(level)})
+	}
+
+	return texture
+}
+
+// Box blur of a square texture that wraps around at the edges
+func wrappingBoxBlur(values []float64, radius int) []float64 {
+	blur := func(values []float64, offset func(x int, y int) int) []float64 {
+		result := make([]float64, len(values))
+		for y := 0; y < GRAIN_TEXTURE_SIZE; y++ {
+			for x := 0; x < GRAIN_TEXTURE_SIZE; x++ {
+				sum := 0.0
+				for delta := -radius; delta <= radius; delta++ {
+					sum += values[offset((x+delta+GRAIN_TEXTURE_SIZE)%GRAIN_TEXTURE_SIZE, y)]
+				}
+				result[offset(x, y)] = sum / float64(2*radius+1)
+			}
+		}
+		return result
+	}
+
+	horizontal := blur(values, func(x int, y int) int { return y*GRAIN_TEXTURE_SIZE + x })
+	return blur(horizontal, func(x int, y int) int { return x*GRAIN_TEXTURE_SIZE + y })
+}
+
+// Scales the values to a mean of 0 and a standard deviation of 1
+func normalizeDeviation(values []float64) {
+	mean := 0.0
+	for _, value := range values {
+		mean += value
+	}
+	mean /= float64(len(values))
+
+	variance := 0.0
+	for _, value := range values {
+		variance += (value - mean) * (value - mean)
+	}
+	deviation := math.Sqrt(variance / float64(len(values)))
+
+	for index := range values {
+		values[index] = (values[index] - mean) / deviation
+	}
+}
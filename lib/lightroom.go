@@ -4,13 +4,15 @@ import (
 	"encoding/xml"
 	"fmt"
 	"io"
+	"log"
 	"strconv"
 	"strings"
 )
 
+// A point of a tone curve in the range of 0 - 255. Newer versions of lightroom write fractional values.
 type LightroomToneCurvePoint struct {
-	In  int
-	Out int
+	In  float64
+	Out float64
 }
 
 // Example tone curve point:
@@ -22,16 +24,27 @@ func (self *LightroomToneCurvePoint) UnmarshalXML(d *xml.Decoder, start xml.Star
 		return err
 	}
 
+	// The element has to be consumed completely, even if the point is invalid, so that
+	// the curve can continue with the next point. Skipping reuses the buffer of the text.
+	text = xml.CopyToken(text)
+	err = d.Skip()
+	if err != nil {
+		return err
+	}
+
 	parts := strings.Split(fmt.Sprintf("%s", text), ",")
+	if len(parts) != 2 {
+		return fmt.Errorf("invalid tone curve point '%s'", text)
+	}
 	inStr := strings.Trim(parts[0], " ")
 	outStr := strings.Trim(parts[1], " ")
 
-	in, err := strconv.Atoi(inStr)
+	in, err := strconv.ParseFloat(inStr, 64)
 	if err != nil {
 		return err
 	}
 
-	out, err := strconv.Atoi(outStr)
+	out, err := strconv.ParseFloat(outStr, 64)
 	if err != nil {
 		return err
 	}
@@ -39,7 +52,7 @@ func (self *LightroomToneCurvePoint) UnmarshalXML(d *xml.Decoder, start xml.Star
 	self.In = in
 	self.Out = out
 
-	return d.Skip()
+	return nil
 }
 
 type LightroomToneCurve struct {
@@ -72,13 +85,18 @@ func (self *LightroomToneCurve) UnmarshalXML(d *xml.Decoder, start xml.StartElem
 			element := tok.(xml.StartElement)
 			if element.Name.Local == "li" {
 				point := LightroomToneCurvePoint{}
-				d.DecodeElement(&point, &element)
+				err = d.DecodeElement(&point, &element)
+				if err != nil {
+					log.Printf("[WARN] Skipping a point of %s: %s", start.Name.Local, err)
+					continue
+				}
 				self.Points = append(self.Points, point)
 			}
 		}
 	}
 
-	return d.Skip()
+	// The decoder returns EOF at the end of the curve element, so there is nothing left to skip
+	return nil
 }
 
 type LightroomCombinedToneCurve struct {
@@ -89,8 +107,16 @@ type LightroomCombinedToneCurve struct {
 }
 
 type LightroomPreset struct {
+	Settings LightroomDevelopSettings
+
+	// Settings that are not part of the typed settings, by their lightroom name
 	Attributes map[string]string
-	ToneCurve  LightroomCombinedToneCurve
+
+	// Graduated filters, radial filters, brushes and mask groups
+	LocalCorrections []LightroomLocalCorrection
+
+	// EXIF orientation of the photo the settings belong to, 0 if unknown (e.g. for presets)
+	Orientation int
 }
 
 func (self *LightroomPreset) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
@@ -109,20 +135,40 @@ func (self *LightroomPreset) UnmarshalXML(d *xml.Decoder, start xml.StartElement
 			switch element.Name.Local {
 			case "Description":
 				for _, attribute := range element.Attr {
-					self.Attributes[attribute.Name.Local] = attribute.Value
+					if attribute.Name.Space == TIFF_NAMESPACE && attribute.Name.Local == "Orientation" {
+						self.Orientation, _ = strconv.Atoi(strings.TrimSpace(attribute.Value))
+						continue
+					}
+
+					// Sidecars and embedded XMP also contain exif, tiff, dc, ... attributes which
+					// have nothing to do with the develop settings.
+					if attribute.Name.Space != LIGHTROOM_CRS_NAMESPACE {
+						continue
+					}
+					self.Set(attribute.Name.Local, attribute.Value)
 				}
 				break
+			case LIGHTROOM_LOCAL_GRADIENT, LIGHTROOM_LOCAL_CIRCULAR_GRADIENT, LIGHTROOM_LOCAL_PAINT, LIGHTROOM_LOCAL_MASK_GROUP:
+				corrections, err := decodeLightroomLocalCorrections(d, element.Name.Local)
+				if err != nil {
+					return err
+				}
+				self.LocalCorrections = append(self.LocalCorrections, corrections...)
+				break
+			case "ToneCurve":
+				d.DecodeElement(&self.Settings.LegacyToneCurve, &element)
+				break
 			case "ToneCurvePV2012":
-				d.DecodeElement(&self.ToneCurve.Rgb, &element)
+				d.DecodeElement(&self.Settings.ToneCurve.Rgb, &element)
 				break
 			case "ToneCurvePV2012Red":
-				d.DecodeElement(&self.ToneCurve.Red, &element)
+				d.DecodeElement(&self.Settings.ToneCurve.Red, &element)
 				break
 			case "ToneCurvePV2012Green":
-				d.DecodeElement(&self.ToneCurve.Green, &element)
+				d.DecodeElement(&self.Settings.ToneCurve.Green, &element)
 				break
 			case "ToneCurvePV2012Blue":
-				d.DecodeElement(&self.ToneCurve.Blue, &element)
+				d.DecodeElement(&self.Settings.ToneCurve.Blue, &element)
 				break
 			}
 		}
@@ -136,3 +182,38 @@ func NewLightroomPreset() LightroomPreset {
 	preset.Attributes = make(map[string]string)
 	return preset
 }
+
+// Sets a setting from its XMP / catalog representation
+func (self *LightroomPreset) Set(name string, value string) {
+	known, err := self.Settings.Set(name, value)
+	if err != nil {
+		log.Printf("[WARN] %s", err)
+	}
+	if !known || err != nil {
+		self.Attributes[name] = value
+	}
+}
+
+// Removes a setting from the preset
+func (self *LightroomPreset) Remove(name string) {
+	if lightroomLocalCorrectionKinds[name] {
+		corrections := []LightroomLocalCorrection{}
+		for _, correction := range self.LocalCorrections {
+			if correction.Kind != name {
+				corrections = append(corrections, correction)
+			}
+		}
+		self.LocalCorrections = corrections
+		return
+	}
+
+	if !self.Settings.Remove(name) {
+		delete(self.Attributes, name)
+	}
+}
+
+func (self *LightroomPreset) RemoveAll(names []string) {
+	for _, name := range names {
+		self.Remove(name)
+	}
+}
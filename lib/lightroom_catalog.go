@@ -0,0 +1,309 @@
+package lib
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const CATALOG_ENTRY_PRESET = "preset"
+const CATALOG_ENTRY_SNAPSHOT = "snapshot"
+const CATALOG_ENTRY_VIRTUAL_COPY = "virtual copy"
+
+// A set of develop settings found in a lightroom catalog.
+// Group is the file name of the photo for snapshots and virtual copies and the folder of presets.
+type LightroomCatalogEntry struct {
+	Kind   string
+	Group  string
+	Name   string
+	Preset LightroomPreset
+}
+
+/*
+ * Reads all develop settings from a lightroom classic catalog (.lrcat):
+ *
+ *		- Develop snapshots (Adobe_libraryImageDevelopSnapshot)
+ *		- Develop settings of virtual copies (Adobe_images with a masterImage + Adobe_imageDevelopSettings)
+ *		- Develop presets stored with the catalog. These are not part of the database but live
+ *		  in the `Lightroom Settings` folder next to it.
+ *
+ * Snapshots and virtual copies are the settings of photos, the policy decides which per-image
+ * settings are kept for them.
+ */
+func ReadLightroomCatalog(path string, policy PhotoSettingsPolicy) ([]LightroomCatalogEntry, error) {
+	database, err := openSqliteDatabase(path)
+	if err != nil {
+		return nil, err
+	}
+	defer database.Close()
+
+	images, err := lightroomCatalogImages(database)
+	if err != nil {
+		return nil, err
+	}
+	imagesById := map[interface{}]lightroomCatalogImage{}
+	for _, image := range images {
+		imagesById[image.Id] = image
+	}
+
+	entries, err := readLightroomCatalogPresets(filepath.Join(filepath.Dir(path), "Lightroom Settings", "Develop Presets"))
+	if err != nil {
+		return nil, err
+	}
+
+	snapshots, err := database.rows("Adobe_libraryImageDevelopSnapshot")
+	if err != nil {
+		return nil, err
+	}
+	for _, snapshot := range snapshots {
+		image := imagesById[snapshot["image"]]
+		entry, valid := newLightroomCatalogPhotoEntry(CATALOG_ENTRY_SNAPSHOT, fmt.Sprint(snapshot["name"]), snapshot["text"], image, policy)
+		if valid {
+			entries = append(entries, entry)
+		}
+	}
+
+	virtualCopies, err := lightroomCatalogVirtualCopies(database, images, policy)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(entries, virtualCopies...), nil
+}
+
+// Creates the entry of a snapshot or virtual copy from its develop settings. Settings that cannot
+// be read are reported and skipped, so that a single broken photo does not prevent reading
+// the rest of the catalog.
+func newLightroomCatalogPhotoEntry(kind string, name string, settings interface{}, image lightroomCatalogImage, policy PhotoSettingsPolicy) (LightroomCatalogEntry, bool) {
+	text, isText := settings.(string)
+	if !isText {
+		log.Printf("[WARN] The %s '%s' of %s has no develop settings and will be skipped.", kind, name, image.FileName)
+		return LightroomCatalogEntry{}, false
+	}
+
+	preset, err := NewLightroomPresetFromLua(text)
+	if err != nil {
+		log.Printf("[WARN] The develop settings of the %s '%s' of %s cannot be read and will be skipped: %s", kind, name, image.FileName, err)
+		return LightroomCatalogEntry{}, false
+	}
+
+	policy.Apply(&preset)
+	preset.Orientation = image.Orientation
+	return LightroomCatalogEntry{
+		Kind:   kind,
+		Group:  image.FileName,
+		Name:   name,
+		Preset: preset,
+	}, true
+}
+
+// Lightroom stores the orientation of a photo as the corners of the displayed image (A top left,
+// B top right, C bottom right, D bottom left) that the top left and the top right corner of the
+// stored image end up in. Mapped to the EXIF orientation.
+var lightroomCatalogOrientations = map[string]int{
+	"AB": 1,
+	"BA": 2,
+	"CD": 3,
+	"DC": 4,
+	"AD": 5,
+	"BC": 6,
+	"CB": 7,
+	"DA": 8,
+}
+
+// What the develop settings of a photo need to know about the image
+type lightroomCatalogImage struct {
+	Id          interface{}
+	FileName    string
+	Orientation int
+
+	// Virtual copies have the master image they were created from and a name
+	MasterImage interface{}
+	CopyName    string
+}
+
+// Reads the images of the catalog with their file names and orientations
+func lightroomCatalogImages(database *sqliteDatabase) ([]lightroomCatalogImage, error) {
+	files, err := database.rows("AgLibraryFile")
+	if err != nil {
+		return nil, err
+	}
+
+	fileNamesById := map[interface{}]string{}
+	for _, file := range files {
+		fileNamesById[file["id_local"]] = fmt.Sprint(file["idx_filename"])
+	}
+
+	rows, err := database.rows("Adobe_images")
+	if err != nil {
+		return nil, err
+	}
+
+	images := make([]lightroomCatalogImage, len(rows))
+	for index, row := range rows {
+		fileName := fileNamesById[row["rootFile"]]
+
+		orientation := 0
+		if code, isString := row["orientation"].(string); isString && code != "" {
+			known := false
+			orientation, known = lightroomCatalogOrientations[code]
+			if !known {
+				log.Printf("[WARN] Unknown orientation '%s' of %s, crops are converted as if the photo was not rotated.", code, fileName)
+			}
+		}
+
+		copyName, _ := row["copyName"].(string)
+		images[index] = lightroomCatalogImage{
+			Id:          row["id_local"],
+			FileName:    fileName,
+			Orientation: orientation,
+			MasterImage: row["masterImage"],
+			CopyName:    copyName,
+		}
+	}
+
+	return images, nil
+}
+
+func lightroomCatalogVirtualCopies(database *sqliteDatabase, images []lightroomCatalogImage, policy PhotoSettingsPolicy) ([]LightroomCatalogEntry, error) {
+	settings, err := database.rows("Adobe_imageDevelopSettings")
+	if err != nil {
+		return nil, err
+	}
+
+	settingsByImage := map[interface{}]interface{}{}
+	for _, setting := range settings {
+		settingsByImage[setting["image"]] = setting["text"]
+	}
+
+	entries := []LightroomCatalogEntry{}
+	for _, image := range images {
+		if image.MasterImage == nil {
+			continue
+		}
+
+		entry, valid := newLightroomCatalogPhotoEntry(CATALOG_ENTRY_VIRTUAL_COPY, image.CopyName, settingsByImage[image.Id], image, policy)
+		if valid {
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries, nil
+}
+
+// Reads `.lrtemplate` and `.xmp` presets stored alongside the catalog
+func readLightroomCatalogPresets(directory string) ([]LightroomCatalogEntry, error) {
+	entries := []LightroomCatalogEntry{}
+
+	_, err := os.Stat(directory)
+	if os.IsNotExist(err) {
+		return entries, nil
+	}
+
+	err = filepath.Walk(directory, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+
+		extension := strings.ToLower(filepath.Ext(path))
+		if extension != ".lrtemplate" && extension != ".xmp" {
+			return nil
+		}
+
+		contents, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		preset := NewLightroomPreset()
+		if extension == ".xmp" {
+			err = xml.Unmarshal(contents, &preset)
+		} else {
+			preset, err = NewLightroomPresetFromLua(string(contents))
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %s", path, err)
+		}
+
+		folder, err := filepath.Rel(directory, filepath.Dir(path))
+		if err != nil || folder == "." {
+			folder = ""
+		}
+
+		entries = append(entries, LightroomCatalogEntry{
+			Kind:   CATALOG_ENTRY_PRESET,
+			Group:  filepath.ToSlash(folder),
+			Name:   strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)),
+			Preset: preset,
+		})
+		return nil
+	})
+
+	return entries, err
+}
+
+// Parses lua encoded develop settings. These may either be the settings table itself
+// (catalog) or a `.lrtemplate` preset which wraps the settings in `value.settings`.
+func NewLightroomPresetFromLua(source string) (LightroomPreset, error) {
+	preset := NewLightroomPreset()
+
+	table, err := parseLuaTable(source)
+	if err != nil {
+		return preset, err
+	}
+
+	if value, isTable := table.Fields["value"].(luaTable); isTable {
+		table = value
+	}
+	if settings, isTable := table.Fields["settings"].(luaTable); isTable {
+		table = settings
+	}
+
+	curves := map[string]*LightroomToneCurve{
+		"ToneCurve":            &preset.Settings.LegacyToneCurve,
+		"ToneCurvePV2012":      &preset.Settings.ToneCurve.Rgb,
+		"ToneCurvePV2012Red":   &preset.Settings.ToneCurve.Red,
+		"ToneCurvePV2012Green": &preset.Settings.ToneCurve.Green,
+		"ToneCurvePV2012Blue":  &preset.Settings.ToneCurve.Blue,
+	}
+
+	for key, field := range table.Fields {
+		switch value := field.(type) {
+		case string:
+			preset.Set(key, value)
+		case float64:
+			preset.Set(key, strconv.FormatFloat(value, 'f', -1, 64))
+		case bool:
+			// Same spelling as in XMP files
+			if value {
+				preset.Set(key, "True")
+			} else {
+				preset.Set(key, "False")
+			}
+		case luaTable:
+			if lightroomLocalCorrectionKinds[key] {
+				preset.LocalCorrections = append(preset.LocalCorrections, newLightroomLocalCorrectionsFromLua(key, value)...)
+				continue
+			}
+
+			curve, isCurve := curves[key]
+			if !isCurve {
+				continue
+			}
+
+			// Curves are flat lists of alternating input and output values
+			for index := 0; index+1 < len(value.List); index += 2 {
+				in, _ := value.List[index].(float64)
+				out, _ := value.List[index+1].(float64)
+				curve.Points = append(curve.Points, LightroomToneCurvePoint{In: in, Out: out})
+			}
+		}
+	}
+
+	return preset, nil
+}
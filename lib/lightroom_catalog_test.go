@@ -0,0 +1,150 @@
+package lib
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var testCatalog = filepath.Join("testdata", "catalog", "Catalog.lrcat")
+
+func findCatalogEntry(t *testing.T, entries []LightroomCatalogEntry, kind string, name string) LightroomCatalogEntry {
+	for _, entry := range entries {
+		if entry.Kind == kind && entry.Name == name {
+			return entry
+		}
+	}
+
+	t.Fatalf("catalog has no %s '%s', entries: %+v", kind, name, entries)
+	return LightroomCatalogEntry{}
+}
+
+func TestReadLightroomCatalogEntries(t *testing.T) {
+	entries, err := ReadLightroomCatalog(testCatalog, NewPhotoSettingsPolicy())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 entries, got %d: %+v", len(entries), entries)
+	}
+
+	preset := findCatalogEntry(t, entries, CATALOG_ENTRY_PRESET, "Warm")
+	if preset.Group != "User Presets" {
+		t.Errorf("preset group = '%s', expected 'User Presets'", preset.Group)
+	}
+	if preset.Preset.Settings.Vibrance != (LightroomSlider{Value: 25, Present: true}) {
+		t.Errorf("preset Vibrance = %+v", preset.Preset.Settings.Vibrance)
+	}
+	if preset.Preset.Settings.Temperature != (LightroomSlider{Value: 6500, Present: true}) {
+		t.Errorf("preset Temperature = %+v", preset.Preset.Settings.Temperature)
+	}
+
+	snapshot := findCatalogEntry(t, entries, CATALOG_ENTRY_SNAPSHOT, "Final")
+	if snapshot.Group != "IMG_0001.CR2" {
+		t.Errorf("snapshot group = '%s', expected 'IMG_0001.CR2'", snapshot.Group)
+	}
+	if snapshot.Preset.Settings.Exposure2012 != (LightroomSlider{Value: 0.35, Present: true}) {
+		t.Errorf("snapshot Exposure2012 = %+v", snapshot.Preset.Settings.Exposure2012)
+	}
+	if len(snapshot.Preset.Settings.ToneCurve.Rgb.Points) != 5 {
+		t.Errorf("snapshot tone curve = %+v", snapshot.Preset.Settings.ToneCurve.Rgb.Points)
+	}
+
+	virtualCopy := findCatalogEntry(t, entries, CATALOG_ENTRY_VIRTUAL_COPY, "Copy 1")
+	if virtualCopy.Group != "IMG_0001.CR2" {
+		t.Errorf("virtual copy group = '%s', expected 'IMG_0001.CR2'", virtualCopy.Group)
+	}
+	if virtualCopy.Preset.Settings.Saturation != (LightroomSlider{Value: -20, Present: true}) {
+		t.Errorf("virtual copy Saturation = %+v", virtualCopy.Preset.Settings.Saturation)
+	}
+
+	// Virtual copies can be rotated independently of their master
+	if snapshot.Preset.Orientation != 6 || virtualCopy.Preset.Orientation != 8 {
+		t.Errorf("orientation of the snapshot = %d, of the virtual copy = %d, expected 6 and 8", snapshot.Preset.Orientation, virtualCopy.Preset.Orientation)
+	}
+	if preset.Preset.Orientation != 0 {
+		t.Errorf("presets have no orientation, got %d", preset.Preset.Orientation)
+	}
+}
+
+func TestLightroomCatalogOrientationsMatchExif(t *testing.T) {
+	corners := map[byte][2]float64{'A': {0, 0}, 'B': {1, 0}, 'C': {1, 1}, 'D': {0, 1}}
+
+	for code, orientation := range lightroomCatalogOrientations {
+		transform := exifOrientations[orientation].Transform
+		for index, stored := range [][2]float64{{0, 0}, {1, 0}} {
+			x, y := transform(stored[0], stored[1])
+			if [2]float64{x, y} != corners[code[index]] {
+				t.Errorf("%s: corner %v of the stored image is displayed at %v, expected %c", code, stored, [2]float64{x, y}, code[index])
+			}
+		}
+	}
+}
+
+func TestReadLightroomCatalogAppliesPolicyToPhotos(t *testing.T) {
+	entries, err := ReadLightroomCatalog(testCatalog, NewPhotoSettingsPolicy())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	snapshot := findCatalogEntry(t, entries, CATALOG_ENTRY_SNAPSHOT, "Final")
+	if snapshot.Preset.Settings.CropLeft.Present || snapshot.Preset.Settings.HasCrop.Present {
+		t.Errorf("crop of the snapshot was kept")
+	}
+	if snapshot.Preset.Settings.Temperature.Present || snapshot.Preset.Settings.WhiteBalance != "" {
+		t.Errorf("white balance 'As Shot' of the snapshot was kept")
+	}
+	if len(snapshot.Preset.LocalCorrections) != 0 {
+		t.Errorf("local corrections of the snapshot were kept")
+	}
+
+	virtualCopy := findCatalogEntry(t, entries, CATALOG_ENTRY_VIRTUAL_COPY, "Copy 1")
+	if virtualCopy.Preset.Settings.Temperature.Present {
+		t.Errorf("white balance 'As Shot' of the virtual copy was kept")
+	}
+
+	// Presets are not photos, their white balance is a part of the look
+	preset := findCatalogEntry(t, entries, CATALOG_ENTRY_PRESET, "Warm")
+	if !preset.Preset.Settings.Temperature.Present {
+		t.Errorf("white balance of the preset was removed")
+	}
+
+	kept, err := ReadLightroomCatalog(testCatalog, PhotoSettingsPolicy{KeepCrop: true, KeepLocalAdjustments: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	snapshot = findCatalogEntry(t, kept, CATALOG_ENTRY_SNAPSHOT, "Final")
+	if snapshot.Preset.Settings.CropLeft != (LightroomSlider{Value: 0.1, Present: true}) {
+		t.Errorf("snapshot CropLeft = %+v", snapshot.Preset.Settings.CropLeft)
+	}
+	if len(snapshot.Preset.LocalCorrections) != 1 {
+		t.Errorf("expected 1 local correction, got %d", len(snapshot.Preset.LocalCorrections))
+	}
+}
+
+func TestReadLightroomCatalogSkipsBrokenSettings(t *testing.T) {
+	var entries []LightroomCatalogEntry
+	var err error
+	output := captureLog(func() {
+		entries, err = ReadLightroomCatalog(testCatalog, NewPhotoSettingsPolicy())
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The snapshot without settings, the garbled snapshot and the garbled virtual copy are left out
+	if len(entries) != 3 {
+		t.Errorf("expected 3 entries, got %d: %+v", len(entries), entries)
+	}
+	for _, expected := range []string{
+		"[WARN] The snapshot 'Empty' of IMG_0001.CR2 has no develop settings and will be skipped.",
+		"[WARN] The develop settings of the snapshot 'Garbled' of IMG_0001.CR2 cannot be read and will be skipped",
+		"[WARN] The develop settings of the virtual copy 'Copy 2' of IMG_0001.CR2 cannot be read and will be skipped",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("expected '%s' in the log, got '%s'", expected, output)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package lib
+
+import (
+	"strconv"
+)
+
+const LIGHTROOM_PROCESS_VERSION_2003 = "PV2003"
+const LIGHTROOM_PROCESS_VERSION_2010 = "PV2010"
+const LIGHTROOM_PROCESS_VERSION_2012 = "PV2012"
+
+// Defaults that are the same in all process versions. Sharpening and noise reduction
+// use the defaults lightroom applies to raw files.
+var lightroomCommonDefaults = map[string]float64{
+	"ParametricShadowSplit":         25,
+	"ParametricMidtoneSplit":        50,
+	"ParametricHighlightSplit":      75,
+	"ColorGradeBlending":            50,
+	"Sharpness":                     25,
+	"SharpenRadius":                 1,
+	"SharpenDetail":                 25,
+	"LuminanceNoiseReductionDetail": 50,
+	"ColorNoiseReduction":           25,
+	"ColorNoiseReductionDetail":     50,
+	"ColorNoiseReductionSmoothness": 50,
+	"PostCropVignetteMidpoint":      50,
+	"PostCropVignetteFeather":       50,
+	"PostCropVignetteStyle":         1,
+	"VignetteMidpoint":              50,
+	"DefringePurpleHueLo":           30,
+	"DefringePurpleHueHi":           70,
+	"DefringeGreenHueLo":            40,
+	"DefringeGreenHueHi":            60,
+	"PerspectiveScale":              100,
+	"CropBottom":                    1,
+	"CropRight":                     1,
+	"GrainSize":                     25,
+	"GrainFrequency":                50,
+}
+
+// Slider defaults per process version. Sliders that are not listed default to 0.
+var lightroomProcessVersionDefaults = map[string]map[string]float64{
+	LIGHTROOM_PROCESS_VERSION_2012: {
+		"Sharpness": 40,
+	},
+	LIGHTROOM_PROCESS_VERSION_2010: {
+		"Shadows":    5,
+		"Brightness": 50,
+		"Contrast":   25,
+	},
+	LIGHTROOM_PROCESS_VERSION_2003: {
+		"Shadows":    5,
+		"Brightness": 50,
+		"Contrast":   25,
+	},
+}
+
+// Returns the process version family (PV2003, PV2010 or PV2012) of the settings.
+// For presets without a valid process version the family is inferred from the sliders.
+func (self LightroomDevelopSettings) ProcessVersionFamily() string {
+	version, err := strconv.ParseFloat(self.ProcessVersion, 64)
+	if err != nil {
+		return self.inferredProcessVersionFamily()
+	}
+	if version >= 6 {
+		return LIGHTROOM_PROCESS_VERSION_2012
+	}
+	if version >= 5.7 {
+		return LIGHTROOM_PROCESS_VERSION_2010
+	}
+	return LIGHTROOM_PROCESS_VERSION_2003
+}
+
+// Sliders that only exist in PV2012, in addition to the replacements of the legacy sliders
+var lightroomProcessVersion2012Sliders = []string{"Whites2012"}
+
+// Presets that only contain legacy sliders or the legacy tone curve were made for PV2010 (or earlier,
+// which uses the same defaults). All other presets are treated as PV2012.
+func (self LightroomDevelopSettings) inferredProcessVersionFamily() string {
+	isPresent := func(name string) bool {
+		field, _ := self.field(name)
+		return field.Interface().(LightroomSlider).Present
+	}
+
+	current := self.ToneCurveName2012 != "" || len(self.ToneCurve.Rgb.Points) > 0
+	legacy := self.ToneCurveName != "" || len(self.LegacyToneCurve.Points) > 0
+	for _, upgrade := range lightroomSliderUpgrades {
+		current = current || isPresent(upgrade.Replacement)
+		legacy = legacy || isPresent(upgrade.Legacy)
+	}
+	for _, name := range lightroomProcessVersion2012Sliders {
+		current = current || isPresent(name)
+	}
+
+	if legacy && !current {
+		return LIGHTROOM_PROCESS_VERSION_2010
+	}
+	return LIGHTROOM_PROCESS_VERSION_2012
+}
+
+// Returns the value lightroom uses for the given slider if it is not changed
+func (self LightroomDevelopSettings) Default(name string) float64 {
+	value, exists := lightroomProcessVersionDefaults[self.ProcessVersionFamily()][name]
+	if exists {
+		return value
+	}
+	return lightroomCommonDefaults[name]
+}
+
+// A slider is changed if it is present in the preset and differs from the default
+func (self LightroomDevelopSettings) IsChanged(name string) bool {
+	field, exists := self.field(name)
+	if !exists {
+		return false
+	}
+
+	slider, isSlider := field.Interface().(LightroomSlider)
+	return isSlider && slider.Present && slider.Value != self.Default(name)
+}
+
+// Returns the value of the slider, or its default if it is not present in the preset
+func (self LightroomDevelopSettings) Value(name string) float64 {
+	field, exists := self.field(name)
+	if !exists {
+		return 0
+	}
+
+	slider, isSlider := field.Interface().(LightroomSlider)
+	if !isSlider || !slider.Present {
+		return self.Default(name)
+	}
+	return slider.Value
+}
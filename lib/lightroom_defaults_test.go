@@ -0,0 +1,53 @@
+package lib
+
+import (
+	"testing"
+)
+
+func TestProcessVersionFamily(t *testing.T) {
+	tests := []struct {
+		settings map[string]string
+		expected string
+	}{
+		{map[string]string{"ProcessVersion": "11.0"}, LIGHTROOM_PROCESS_VERSION_2012},
+		{map[string]string{"ProcessVersion": "6.7", "Brightness": "60"}, LIGHTROOM_PROCESS_VERSION_2012},
+		{map[string]string{"ProcessVersion": "5.7"}, LIGHTROOM_PROCESS_VERSION_2010},
+		{map[string]string{"ProcessVersion": "5.0"}, LIGHTROOM_PROCESS_VERSION_2003},
+
+		// Without a process version the family is inferred from the sliders
+		{map[string]string{}, LIGHTROOM_PROCESS_VERSION_2012},
+		{map[string]string{"Exposure2012": "0.5"}, LIGHTROOM_PROCESS_VERSION_2012},
+		{map[string]string{"Brightness": "60", "Recovery": "20"}, LIGHTROOM_PROCESS_VERSION_2010},
+		{map[string]string{"ProcessVersion": "unknown", "FillLight": "10"}, LIGHTROOM_PROCESS_VERSION_2010},
+		{map[string]string{"ToneCurveName": "Medium Contrast"}, LIGHTROOM_PROCESS_VERSION_2010},
+		{map[string]string{"Contrast": "30", "Whites2012": "10"}, LIGHTROOM_PROCESS_VERSION_2012},
+		{map[string]string{"Clarity": "30", "ToneCurveName2012": "Linear"}, LIGHTROOM_PROCESS_VERSION_2012},
+	}
+
+	for _, test := range tests {
+		preset := NewLightroomPreset()
+		for name, value := range test.settings {
+			preset.Set(name, value)
+		}
+
+		family := preset.Settings.ProcessVersionFamily()
+		if family != test.expected {
+			t.Errorf("process version family of %v = %s, expected %s", test.settings, family, test.expected)
+		}
+	}
+}
+
+func TestLegacyPresetWithoutProcessVersionIsUpgraded(t *testing.T) {
+	preset := NewLightroomPreset()
+	preset.Set("Brightness", "50")
+	preset.Set("Contrast", "35")
+
+	// Brightness 50 and Contrast 25 are the legacy defaults, so only the contrast changes
+	upgraded := preset.Settings.UpgradedToProcessVersion2012()
+	if upgraded.Contrast2012 != (LightroomSlider{Value: 10, Present: true}) {
+		t.Errorf("Contrast2012 = %+v, expected 10", upgraded.Contrast2012)
+	}
+	if upgraded.Exposure2012.Present || upgraded.Brightness.Present || upgraded.Contrast.Present {
+		t.Errorf("legacy sliders should be replaced, got %+v", upgraded)
+	}
+}
@@ -0,0 +1,226 @@
+package lib
+
+import (
+	"encoding/xml"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const LIGHTROOM_LOCAL_GRADIENT = "GradientBasedCorrections"
+const LIGHTROOM_LOCAL_CIRCULAR_GRADIENT = "CircularGradientBasedCorrections"
+const LIGHTROOM_LOCAL_PAINT = "PaintBasedCorrections"
+const LIGHTROOM_LOCAL_MASK_GROUP = "MaskGroupBasedCorrections"
+
+var lightroomLocalCorrectionKinds = map[string]bool{
+	LIGHTROOM_LOCAL_GRADIENT:          true,
+	LIGHTROOM_LOCAL_CIRCULAR_GRADIENT: true,
+	LIGHTROOM_LOCAL_PAINT:             true,
+	LIGHTROOM_LOCAL_MASK_GROUP:        true,
+}
+
+const LIGHTROOM_MASK_GRADIENT = "Mask/Gradient"
+const LIGHTROOM_MASK_CIRCULAR_GRADIENT = "Mask/CircularGradient"
+
+// A mask of a local correction. Masks are identified by `What` (e.g. `Mask/Gradient`),
+// the geometry is kept as it is found in the preset.
+//
+// Linear gradients use ZeroX / ZeroY (no effect) and FullX / FullY (full effect), radial gradients
+// use Top, Left, Bottom, Right, Angle, Feather and Flipped. All coordinates are relative to the image (0 - 1).
+type LightroomCorrectionMask struct {
+	What       string
+	Attributes map[string]string
+}
+
+func (self LightroomCorrectionMask) Number(name string) float64 {
+	value, _ := strconv.ParseFloat(strings.TrimSpace(self.Attributes[name]), 64)
+	return value
+}
+
+func (self LightroomCorrectionMask) Flag(name string) bool {
+	return strings.EqualFold(strings.TrimSpace(self.Attributes[name]), "true")
+}
+
+// A graduated filter, radial filter, brush or mask group of a preset.
+type LightroomLocalCorrection struct {
+	// The setting the correction was found in (see LIGHTROOM_LOCAL_*)
+	Kind   string
+	Name   string
+	Active bool
+
+	// Scales all local settings (the amount slider of mask groups)
+	Amount float64
+
+	// Local adjustments by their lightroom name, e.g. LocalExposure2012
+	Settings map[string]float64
+	Masks    []LightroomCorrectionMask
+}
+
+func newLightroomLocalCorrection(kind string) LightroomLocalCorrection {
+	return LightroomLocalCorrection{
+		Kind:     kind,
+		Active:   true,
+		Amount:   1,
+		Settings: map[string]float64{},
+	}
+}
+
+// Sets a property of the correction from its XMP / catalog representation
+func (self *LightroomLocalCorrection) set(name string, value string) {
+	switch name {
+	case "What":
+		break
+	case "CorrectionName":
+		self.Name = value
+	case "CorrectionActive":
+		self.Active = !strings.EqualFold(strings.TrimSpace(value), "false")
+	case "CorrectionAmount":
+		amount, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err == nil {
+			self.Amount = amount
+		}
+	default:
+		number, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err == nil && strings.HasPrefix(name, "Local") {
+			self.Settings[name] = number
+		}
+	}
+}
+
+// Decodes the `rdf:Seq` of corrections inside of one of the LIGHTROOM_LOCAL_* elements.
+//
+// Example:
+// <crs:GradientBasedCorrections>
+// 	<rdf:Seq>
+// 		<rdf:li>
+// 			<rdf:Description crs:What="Correction" crs:CorrectionAmount="1.000000" crs:LocalExposure2012="-0.500000">
+// 				<crs:CorrectionMasks>
+// 					<rdf:Seq>
+// 						<rdf:li crs:What="Mask/Gradient" crs:ZeroX="0.5" crs:ZeroY="0.4" crs:FullX="0.5" crs:FullY="0.1"/>
+// 					</rdf:Seq>
+// 				</crs:CorrectionMasks>
+// 			</rdf:Description>
+// 		</rdf:li>
+// 	</rdf:Seq>
+// </crs:GradientBasedCorrections>
+func decodeLightroomLocalCorrections(d *xml.Decoder, kind string) ([]LightroomLocalCorrection, error) {
+	corrections := []LightroomLocalCorrection{}
+	depth := 1
+	masksDepth := 0
+
+	for depth > 0 {
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return corrections, err
+		}
+
+		switch element := tok.(type) {
+		case xml.StartElement:
+			depth++
+			if element.Name.Local == "CorrectionMasks" {
+				masksDepth = depth
+				continue
+			}
+
+			what := ""
+			for _, attribute := range element.Attr {
+				if attribute.Name.Local == "What" {
+					what = attribute.Value
+				}
+			}
+
+			// Masks may either be attributes of the list item or of a nested description.
+			// Mask groups additionally nest masks of masks, which are all collected.
+			if masksDepth > 0 && len(corrections) > 0 {
+				if strings.HasPrefix(what, "Mask/") {
+					mask := LightroomCorrectionMask{What: what, Attributes: map[string]string{}}
+					for _, attribute := range element.Attr {
+						mask.Attributes[attribute.Name.Local] = attribute.Value
+					}
+					current := &corrections[len(corrections)-1]
+					current.Masks = append(current.Masks, mask)
+				}
+				continue
+			}
+
+			if what == "Correction" {
+				correction := newLightroomLocalCorrection(kind)
+				for _, attribute := range element.Attr {
+					correction.set(attribute.Name.Local, attribute.Value)
+				}
+				corrections = append(corrections, correction)
+			}
+
+		case xml.EndElement:
+			if depth == masksDepth {
+				masksDepth = 0
+			}
+			depth--
+		}
+	}
+
+	return corrections, nil
+}
+
+// Converts a lua table of corrections, which uses the same names as the XMP representation
+func newLightroomLocalCorrectionsFromLua(kind string, table luaTable) []LightroomLocalCorrection {
+	corrections := []LightroomLocalCorrection{}
+	for _, item := range table.List {
+		fields, isTable := item.(luaTable)
+		if !isTable {
+			continue
+		}
+
+		correction := newLightroomLocalCorrection(kind)
+		for name, value := range fields.Fields {
+			switch value := value.(type) {
+			case string:
+				correction.set(name, value)
+			case float64:
+				correction.set(name, strconv.FormatFloat(value, 'f', -1, 64))
+			case bool:
+				correction.set(name, strconv.FormatBool(value))
+			case luaTable:
+				if name == "CorrectionMasks" {
+					correction.Masks = append(correction.Masks, newLightroomCorrectionMasksFromLua(value)...)
+				}
+			}
+		}
+		corrections = append(corrections, correction)
+	}
+	return corrections
+}
+
+func newLightroomCorrectionMasksFromLua(table luaTable) []LightroomCorrectionMask {
+	masks := []LightroomCorrectionMask{}
+	for _, item := range table.List {
+		fields, isTable := item.(luaTable)
+		if !isTable {
+			continue
+		}
+
+		mask := LightroomCorrectionMask{Attributes: map[string]string{}}
+		for name, value := range fields.Fields {
+			switch value := value.(type) {
+			case string:
+				mask.Attributes[name] = value
+			case float64:
+				mask.Attributes[name] = strconv.FormatFloat(value, 'f', -1, 64)
+			case bool:
+				mask.Attributes[name] = strconv.FormatBool(value)
+			case luaTable:
+				// Masks of mask groups
+				masks = append(masks, newLightroomCorrectionMasksFromLua(value)...)
+			}
+		}
+		mask.What = mask.Attributes["What"]
+
+		if strings.HasPrefix(mask.What, "Mask/") {
+			masks = append(masks, mask)
+		}
+	}
+	return masks
+}
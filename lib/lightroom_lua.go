@@ -0,0 +1,265 @@
+package lib
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+/*
+ * Lightroom stores develop settings in catalogs and `.lrtemplate` presets as serialized lua tables:
+ *
+ *		s = {
+ *			Contrast2012 = 15,
+ *			ConvertToGrayscale = false,
+ *			ToneCurveName2012 = "Custom",
+ *			ToneCurvePV2012 = { 0, 18, 39, 28, 255, 240, },
+ *		}
+ *
+ * This is a parser for exactly these table literals, not for lua in general.
+ */
+
+type luaTable struct {
+	Fields map[string]interface{}
+	List   []interface{}
+}
+
+type luaParser struct {
+	source   string
+	position int
+}
+
+// Parses `name = { ... }` or a bare table constructor.
+func parseLuaTable(source string) (luaTable, error) {
+	parser := luaParser{source: source}
+	parser.skipWhitespace()
+
+	// Skip the assignment, if there is one
+	if !strings.HasPrefix(parser.source[parser.position:], "{") {
+		equals := strings.Index(source, "=")
+		if equals == -1 {
+			return luaTable{}, fmt.Errorf("expected lua table")
+		}
+		parser.position = equals + 1
+		parser.skipWhitespace()
+	}
+
+	value, err := parser.value()
+	if err != nil {
+		return luaTable{}, err
+	}
+
+	table, isTable := value.(luaTable)
+	if !isTable {
+		return luaTable{}, fmt.Errorf("expected lua table, got %v", value)
+	}
+	return table, nil
+}
+
+func (self *luaParser) skipWhitespace() {
+	for self.position < len(self.source) {
+		if unicode.IsSpace(rune(self.source[self.position])) {
+			self.position++
+			continue
+		}
+
+		// Line comments
+		if strings.HasPrefix(self.source[self.position:], "--") {
+			newline := strings.Index(self.source[self.position:], "\n")
+			if newline == -1 {
+				self.position = len(self.source)
+			} else {
+				self.position += newline
+			}
+			continue
+		}
+
+		return
+	}
+}
+
+func (self *luaParser) error(message string) error {
+	return fmt.Errorf("lua: %s at offset %d", message, self.position)
+}
+
+func (self *luaParser) value() (interface{}, error) {
+	self.skipWhitespace()
+	if self.position >= len(self.source) {
+		return nil, self.error("unexpected end of input")
+	}
+
+	rest := self.source[self.position:]
+	switch {
+	case rest[0] == '{':
+		return self.table()
+	case rest[0] == '"' || rest[0] == '\'':
+		return self.quotedString()
+	case strings.HasPrefix(rest, "[["):
+		return self.longString()
+	case strings.HasPrefix(rest, "true"):
+		self.position += 4
+		return true, nil
+	case strings.HasPrefix(rest, "false"):
+		self.position += 5
+		return false, nil
+	case strings.HasPrefix(rest, "nil"):
+		self.position += 3
+		return nil, nil
+	}
+
+	return self.number()
+}
+
+func (self *luaParser) table() (luaTable, error) {
+	table := luaTable{Fields: map[string]interface{}{}}
+	self.position++ // {
+
+	for {
+		self.skipWhitespace()
+		if self.position >= len(self.source) {
+			return table, self.error("unterminated table")
+		}
+		if self.source[self.position] == '}' {
+			self.position++
+			return table, nil
+		}
+
+		key, err := self.key()
+		if err != nil {
+			return table, err
+		}
+
+		value, err := self.value()
+		if err != nil {
+			return table, err
+		}
+
+		if key == "" {
+			table.List = append(table.List, value)
+		} else {
+			table.Fields[key] = value
+		}
+
+		self.skipWhitespace()
+		if self.position < len(self.source) && (self.source[self.position] == ',' || self.source[self.position] == ';') {
+			self.position++
+		}
+	}
+}
+
+// Reads `name =` or `["name"] =`. Returns an empty string for positional values.
+func (self *luaParser) key() (string, error) {
+	rest := self.source[self.position:]
+
+	if strings.HasPrefix(rest, "[") && !strings.HasPrefix(rest, "[[") {
+		self.position++
+		keyValue, err := self.value()
+		if err != nil {
+			return "", err
+		}
+		self.skipWhitespace()
+		if !strings.HasPrefix(self.source[self.position:], "]") {
+			return "", self.error("expected ]")
+		}
+		self.position++
+		self.skipWhitespace()
+		if !strings.HasPrefix(self.source[self.position:], "=") {
+			return "", self.error("expected =")
+		}
+		self.position++
+		return fmt.Sprint(keyValue), nil
+	}
+
+	end := 0
+	for end < len(rest) && (rest[end] == '_' || unicode.IsLetter(rune(rest[end])) || (end > 0 && unicode.IsDigit(rune(rest[end])))) {
+		end++
+	}
+	if end == 0 {
+		return "", nil
+	}
+
+	afterName := strings.TrimLeftFunc(rest[end:], unicode.IsSpace)
+	if !strings.HasPrefix(afterName, "=") || strings.HasPrefix(afterName, "==") {
+		// Identifier used as a value (e.g. true), not as a key
+		return "", nil
+	}
+
+	self.position += len(rest) - len(afterName) + 1
+	return rest[:end], nil
+}
+
+func (self *luaParser) quotedString() (string, error) {
+	quote := self.source[self.position]
+	self.position++
+
+	result := strings.Builder{}
+	for self.position < len(self.source) {
+		character := self.source[self.position]
+		self.position++
+
+		switch character {
+		case quote:
+			return result.String(), nil
+		case '\\':
+			if self.position >= len(self.source) {
+				return "", self.error("unterminated escape sequence")
+			}
+			escaped := self.source[self.position]
+			self.position++
+			switch escaped {
+			case 'n':
+				result.WriteByte('\n')
+			case 't':
+				result.WriteByte('\t')
+			case 'r':
+				result.WriteByte('\r')
+			case '\n':
+				result.WriteByte('\n')
+			default:
+				if unicode.IsDigit(rune(escaped)) {
+					// \ddd decimal escapes
+					digits := string(escaped)
+					for len(digits) < 3 && self.position < len(self.source) && unicode.IsDigit(rune(self.source[self.position])) {
+						digits += string(self.source[self.position])
+						self.position++
+					}
+					code, _ := strconv.Atoi(digits)
+					result.WriteByte(byte(code))
+				} else {
+					result.WriteByte(escaped)
+				}
+			}
+		default:
+			result.WriteByte(character)
+		}
+	}
+
+	return "", self.error("unterminated string")
+}
+
+func (self *luaParser) longString() (string, error) {
+	end := strings.Index(self.source[self.position+2:], "]]")
+	if end == -1 {
+		return "", self.error("unterminated long string")
+	}
+
+	value := self.source[self.position+2 : self.position+2+end]
+	self.position += end + 4
+	return strings.TrimPrefix(value, "\n"), nil
+}
+
+func (self *luaParser) number() (float64, error) {
+	end := self.position
+	for end < len(self.source) && strings.ContainsRune("+-.0123456789eE", rune(self.source[end])) {
+		end++
+	}
+
+	value, err := strconv.ParseFloat(self.source[self.position:end], 64)
+	if err != nil {
+		return 0, self.error(fmt.Sprintf("invalid value '%s'", self.source[self.position:end]))
+	}
+
+	self.position = end
+	return value, nil
+}
@@ -0,0 +1,66 @@
+package lib
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseLuaTable(t *testing.T) {
+	table, err := parseLuaTable(`s = {
+		-- a comment with = and { characters
+		Contrast2012 = -15,
+		ConvertToGrayscale = false,
+		["Name With Spaces"] = "quoted \"value\"\n",
+		Long = [[
+line one
+line two]],
+		Curve = { 0, 18; 255, 240, },
+		Nested = { value = { settings = { Exposure2012 = 1.5e-1, }, }, },
+		Missing = nil,
+	}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]interface{}{
+		"Contrast2012":       -15.0,
+		"ConvertToGrayscale": false,
+		"Name With Spaces":   "quoted \"value\"\n",
+		"Long":               "line one\nline two",
+		"Missing":            nil,
+	}
+	for key, value := range expected {
+		if table.Fields[key] != value {
+			t.Errorf("%s = %#v, expected %#v", key, table.Fields[key], value)
+		}
+	}
+
+	curve, _ := table.Fields["Curve"].(luaTable)
+	if !reflect.DeepEqual(curve.List, []interface{}{0.0, 18.0, 255.0, 240.0}) {
+		t.Errorf("Curve = %#v", curve.List)
+	}
+
+	nested, _ := table.Fields["Nested"].(luaTable)
+	value, _ := nested.Fields["value"].(luaTable)
+	settings, _ := value.Fields["settings"].(luaTable)
+	if settings.Fields["Exposure2012"] != 0.15 {
+		t.Errorf("nested Exposure2012 = %#v", settings.Fields["Exposure2012"])
+	}
+}
+
+func TestParseLuaTableErrors(t *testing.T) {
+	sources := []string{
+		``,
+		`s = 15`,
+		`s = { a = 1`,
+		`s = { a = "unterminated }`,
+		`s = { a = [[unterminated }`,
+		`s = { a = 1x }`,
+	}
+
+	for _, source := range sources {
+		if _, err := parseLuaTable(source); err == nil {
+			t.Errorf("expected an error for '%s'", source)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package lib
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+)
+
+// Develop settings of an edited photo contain a lot of things that only make sense for
+// that specific image. These are grouped into categories which can be kept or dropped.
+var photoSpecificAttributes = map[string][]string{
+	"crop": {
+		"CropTop",
+		"CropLeft",
+		"CropBottom",
+		"CropRight",
+		"CropAngle",
+		"CropWidth",
+		"CropHeight",
+		"CropUnit",
+		"CropConstrainToWarp",
+		"CropConstrainAspectRatio",
+		"HasCrop",
+	},
+	"whitebalance": {
+		"WhiteBalance",
+		"Temperature",
+		"Tint",
+	},
+	"local": {
+		"GradientBasedCorrections",
+		"CircularGradientBasedCorrections",
+		"PaintBasedCorrections",
+		"MaskGroupBasedCorrections",
+	},
+	"spots": {
+		"RetouchInfo",
+		"RetouchAreas",
+	},
+}
+
+// Attributes that are only bookkeeping of the photo and are always dropped
+var photoMetadataAttributes = []string{
+	"RawFileName",
+	"AlreadyApplied",
+}
+
+// Decides which per-image settings are removed when the develop settings of
+// a photo are used as a preset.
+type PhotoSettingsPolicy struct {
+	KeepCrop             bool
+	KeepWhiteBalance     bool
+	KeepLocalAdjustments bool
+	KeepSpotRemoval      bool
+}
+
+// By default only the look of the photo is kept
+func NewPhotoSettingsPolicy() PhotoSettingsPolicy {
+	return PhotoSettingsPolicy{}
+}
+
+func (self PhotoSettingsPolicy) Apply(preset *LightroomPreset) {
+	for _, name := range photoMetadataAttributes {
+		preset.Remove(name)
+	}
+
+	if !self.KeepCrop {
+		preset.RemoveAll(photoSpecificAttributes["crop"])
+	}
+
+	// Only white balance 'As Shot' belongs to the image. Explicit white balance settings
+	// are a part of the look.
+	if !self.KeepWhiteBalance && preset.Settings.WhiteBalance == LIGHTROOM_WHITE_BALANCE_AS_SHOT {
+		preset.RemoveAll(photoSpecificAttributes["whitebalance"])
+	}
+
+	if !self.KeepLocalAdjustments {
+		preset.RemoveAll(photoSpecificAttributes["local"])
+	}
+
+	if !self.KeepSpotRemoval {
+		preset.RemoveAll(photoSpecificAttributes["spots"])
+	}
+}
+
+// Finds the XMP packet containing the camera raw settings in an arbitrary file.
+// This works for sidecar files as well as JPEG, DNG and TIFF files which embed the
+// packet as plain text. Files may contain more than one packet (e.g. for embedded previews),
+// the first one containing camera raw settings is used.
+func FindXmpPacket(data []byte) ([]byte, error) {
+	start := []byte("<x:xmpmeta")
+	end := []byte("</x:xmpmeta>")
+
+	remaining := data
+	for {
+		startIndex := bytes.Index(remaining, start)
+		if startIndex == -1 {
+			return nil, errors.New("no XMP packet with camera raw settings found")
+		}
+
+		endIndex := bytes.Index(remaining[startIndex:], end)
+		if endIndex == -1 {
+			return nil, errors.New("XMP packet is not terminated")
+		}
+
+		packet := remaining[startIndex : startIndex+endIndex+len(end)]
+		if bytes.Contains(packet, []byte(LIGHTROOM_CRS_NAMESPACE)) {
+			return packet, nil
+		}
+
+		remaining = remaining[startIndex+endIndex+len(end):]
+	}
+}
+
+// Extracts the develop settings of an edited photo. The data can either be an XMP sidecar
+// or an image file with embedded XMP.
+func NewLightroomPresetFromPhoto(data []byte, policy PhotoSettingsPolicy) (LightroomPreset, error) {
+	preset := NewLightroomPreset()
+
+	packet, err := FindXmpPacket(data)
+	if err != nil {
+		return preset, err
+	}
+
+	err = xml.Unmarshal(packet, &preset)
+	if err != nil {
+		return preset, err
+	}
+
+	policy.Apply(&preset)
+	return preset, nil
+}
@@ -0,0 +1,119 @@
+package lib
+
+import (
+	"bytes"
+	"testing"
+)
+
+func newTestXmpPacket(attributes string) []byte {
+	return []byte(`<x:xmpmeta xmlns:x="adobe:ns:meta/"><rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">` +
+		`<rdf:Description rdf:about="" ` + attributes + `/></rdf:RDF></x:xmpmeta>`)
+}
+
+func TestFindXmpPacket(t *testing.T) {
+	settings := newTestXmpPacket(`xmlns:crs="` + LIGHTROOM_CRS_NAMESPACE + `" crs:Exposure2012="+0.50"`)
+	preview := newTestXmpPacket(`xmlns:xmp="http://ns.adobe.com/xap/1.0/" xmp:Rating="3"`)
+
+	tests := []struct {
+		name     string
+		data     []byte
+		expected []byte
+	}{
+		{
+			"jpeg",
+			bytes.Join([][]byte{{0xff, 0xd8, 0xff, 0xe1, 0x01, 0x00}, []byte("http://ns.adobe.com/xap/1.0/\x00"), settings, {0xff, 0xd9}}, nil),
+			settings,
+		},
+		{
+			// The packet of an embedded preview comes before the settings of the photo
+			"tiff",
+			bytes.Join([][]byte{[]byte("II*\x00\x08\x00\x00\x00"), preview, {0x00, 0x01}, settings, {0x00}}, nil),
+			settings,
+		},
+		{"sidecar", settings, settings},
+		{"no packet", []byte{0xff, 0xd8, 0xff, 0xd9}, nil},
+		{"only other packets", preview, nil},
+		{"unterminated", settings[:len(settings)-4], nil},
+	}
+
+	for _, test := range tests {
+		packet, err := FindXmpPacket(test.data)
+		if test.expected == nil {
+			if err == nil {
+				t.Errorf("%s: expected an error, got packet %s", test.name, packet)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: %s", test.name, err)
+		} else if !bytes.Equal(packet, test.expected) {
+			t.Errorf("%s: found packet %s", test.name, packet)
+		}
+	}
+}
+
+func newTestPhotoPreset(whiteBalance string) LightroomPreset {
+	preset := NewLightroomPreset()
+	preset.Set("RawFileName", "IMG_1234.CR2")
+	preset.Set("HasCrop", "True")
+	preset.Set("CropLeft", "0.1")
+	preset.Set("WhiteBalance", whiteBalance)
+	preset.Set("Temperature", "5200")
+	preset.Set("Exposure2012", "0.5")
+	preset.Set("RetouchInfo", "centerX = 0.5")
+	preset.LocalCorrections = append(preset.LocalCorrections, newLightroomLocalCorrection(LIGHTROOM_LOCAL_GRADIENT))
+	return preset
+}
+
+func TestPhotoSettingsPolicyRemovesPerImageSettings(t *testing.T) {
+	preset := newTestPhotoPreset("As Shot")
+	NewPhotoSettingsPolicy().Apply(&preset)
+
+	if preset.Settings.HasCrop.Present || preset.Settings.CropLeft.Present {
+		t.Errorf("crop should be removed")
+	}
+	if preset.Settings.WhiteBalance != "" || preset.Settings.Temperature.Present {
+		t.Errorf("white balance 'As Shot' should be removed")
+	}
+	if len(preset.LocalCorrections) != 0 {
+		t.Errorf("local corrections should be removed")
+	}
+	if _, exists := preset.Attributes["RetouchInfo"]; exists {
+		t.Errorf("spot removal should be removed")
+	}
+	if _, exists := preset.Attributes["RawFileName"]; exists {
+		t.Errorf("the raw file name should always be removed")
+	}
+	if !preset.Settings.IsChanged("Exposure2012") {
+		t.Errorf("the look of the photo should be kept")
+	}
+}
+
+func TestPhotoSettingsPolicyKeepsRequestedSettings(t *testing.T) {
+	preset := newTestPhotoPreset("As Shot")
+	PhotoSettingsPolicy{KeepCrop: true, KeepWhiteBalance: true, KeepLocalAdjustments: true, KeepSpotRemoval: true}.Apply(&preset)
+
+	if !preset.Settings.HasCrop.Present || !preset.Settings.CropLeft.Present {
+		t.Errorf("crop should be kept")
+	}
+	if preset.Settings.WhiteBalance != LIGHTROOM_WHITE_BALANCE_AS_SHOT || !preset.Settings.Temperature.Present {
+		t.Errorf("white balance should be kept")
+	}
+	if len(preset.LocalCorrections) != 1 {
+		t.Errorf("local corrections should be kept")
+	}
+	if _, exists := preset.Attributes["RetouchInfo"]; !exists {
+		t.Errorf("spot removal should be kept")
+	}
+}
+
+func TestPhotoSettingsPolicyKeepsCustomWhiteBalance(t *testing.T) {
+	// An explicit white balance is part of the look
+	preset := newTestPhotoPreset("Custom")
+	NewPhotoSettingsPolicy().Apply(&preset)
+
+	if preset.Settings.WhiteBalance != "Custom" || !preset.Settings.Temperature.Present {
+		t.Errorf("custom white balance should be kept")
+	}
+}
@@ -0,0 +1,89 @@
+package lib
+
+import (
+	"log"
+	"reflect"
+	"strconv"
+)
+
+// Translates a legacy slider (PV2003 / PV2010) into its PV2012 equivalent.
+// The translation receives the difference of the legacy slider to its default.
+type lightroomSliderUpgrade struct {
+	Legacy      string
+	Replacement string
+	Translate   func(change float64) float64
+}
+
+// Roughly follows what lightroom does when a photo is updated to the current process version.
+// The legacy tone controls work differently, so the results are approximations.
+var lightroomSliderUpgrades = []lightroomSliderUpgrade{
+	{"Exposure", "Exposure2012", func(change float64) float64 { return change }},
+
+	// Recovery only ever darkens highlights
+	{"Recovery", "Highlights2012", func(change float64) float64 { return -change }},
+	{"FillLight", "Shadows2012", func(change float64) float64 { return change }},
+
+	// Legacy 'Shadows' is the black clipping point, higher values mean darker blacks
+	{"Shadows", "Blacks2012", func(change float64) float64 { return -change }},
+
+	// Brightness is a midtone adjustment without a direct replacement. +50 brightness
+	// is about half a stop.
+	{"Brightness", "Exposure2012", func(change float64) float64 { return change / 100 }},
+	{"Contrast", "Contrast2012", func(change float64) float64 { return change }},
+
+	// PV2012 clarity is about twice as strong as the old one
+	{"Clarity", "Clarity2012", func(change float64) float64 { return change / 2 }},
+}
+
+// Returns the settings with all PV2003 / PV2010 sliders and the legacy tone curve translated
+// into their PV2012 equivalents. Settings of the current process version are returned unchanged.
+// Lightroom renders legacy photos with the legacy sliders only, so these win over PV2012 sliders
+// that are also contained in the preset.
+func (self LightroomDevelopSettings) UpgradedToProcessVersion2012() LightroomDevelopSettings {
+	if self.ProcessVersionFamily() == LIGHTROOM_PROCESS_VERSION_2012 {
+		return self
+	}
+
+	if _, err := strconv.ParseFloat(self.ProcessVersion, 64); err != nil {
+		log.Printf("[INFO] Preset has no valid process version but only uses legacy settings, it is treated as %s and translated to the PV2012 equivalents", self.ProcessVersionFamily())
+	} else {
+		log.Printf("[INFO] Preset uses process version %s, legacy settings are translated to their PV2012 equivalents", self.ProcessVersion)
+	}
+
+	upgraded := self
+	translated := map[string]bool{}
+
+	for _, upgrade := range lightroomSliderUpgrades {
+		legacyField, _ := self.field(upgrade.Legacy)
+		legacy := legacyField.Interface().(LightroomSlider)
+		upgraded.Remove(upgrade.Legacy)
+
+		if !self.IsChanged(upgrade.Legacy) {
+			continue
+		}
+
+		// Several legacy sliders may contribute to the same replacement (e.g. exposure and brightness)
+		replacementField, _ := upgraded.field(upgrade.Replacement)
+		replacement := replacementField.Interface().(LightroomSlider)
+		if !translated[upgrade.Replacement] {
+			replacement.Value = 0
+		}
+		translated[upgrade.Replacement] = true
+
+		replacementField.Set(reflect.ValueOf(LightroomSlider{
+			Value:   replacement.Value + upgrade.Translate(legacy.Value-self.Default(upgrade.Legacy)),
+			Present: true,
+		}))
+	}
+
+	if len(self.LegacyToneCurve.Points) > 0 {
+		upgraded.ToneCurve.Rgb = self.LegacyToneCurve
+	}
+	if self.ToneCurveName != "" {
+		upgraded.ToneCurveName2012 = self.ToneCurveName
+	}
+	upgraded.LegacyToneCurve = LightroomToneCurve{}
+	upgraded.ToneCurveName = ""
+
+	return upgraded
+}
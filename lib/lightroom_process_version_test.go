@@ -0,0 +1,47 @@
+package lib
+
+import (
+	"math"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConvertLegacyPreset(t *testing.T) {
+	lightroom := readTestLightroomPreset(t, filepath.Join("testdata", "preset.legacy.xmp"))
+
+	var preset AfterShotPreset
+	output := captureLog(func() {
+		preset = NewAftershotPresetFromLightroom(lightroom)
+	})
+
+	basic := preset.Settings.Basic
+	tests := []struct {
+		option   AfterShotOption
+		expected float64
+	}{
+		// Exposure +0.5 and brightness +25 above its default of 50
+		{basic.Exposure, 0.75},
+		{basic.Contrast, 25},
+		{basic.HighlightRecovery, 30},
+		{basic.FillLight, 0.2},
+		{basic.Vibrance, 10},
+	}
+	for _, test := range tests {
+		if !test.option.IsSet || math.Abs(test.option.Value-test.expected) > 1e-9 {
+			t.Errorf("%s = %s, expected %s", test.option.Name, test.option.Serialize(), formatNumber(test.expected))
+		}
+	}
+
+	if len(preset.Settings.Curves.ToneCurve.Rgb.Points) <= 2 {
+		t.Errorf("the named legacy tone curve should be converted, got %+v", preset.Settings.Curves.ToneCurve.Rgb.Points)
+	}
+
+	// Legacy 'Shadows' becomes the black point, which aftershot cannot express
+	if !strings.Contains(output, "[WARN] The black point cannot be converted to aftershot, Blacks2012 (-5)") {
+		t.Errorf("expected a warning about the black point, got '%s'", output)
+	}
+	if strings.Contains(output, "Cannot lightroom configuration") {
+		t.Errorf("upgraded sliders should all be mapped, got '%s'", output)
+	}
+}
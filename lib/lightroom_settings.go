@@ -0,0 +1,262 @@
+package lib
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// A numeric lightroom setting. Present is false if the preset does not contain the setting.
+type LightroomSlider struct {
+	Value   float64
+	Present bool
+}
+
+// A boolean lightroom setting (`True` / `False` in XMP files).
+type LightroomFlag struct {
+	Value   bool
+	Present bool
+}
+
+type LightroomWhiteBalance string
+
+const LIGHTROOM_WHITE_BALANCE_AS_SHOT LightroomWhiteBalance = "As Shot"
+const LIGHTROOM_WHITE_BALANCE_AUTO LightroomWhiteBalance = "Auto"
+const LIGHTROOM_WHITE_BALANCE_CUSTOM LightroomWhiteBalance = "Custom"
+
+type LightroomToneCurveName string
+
+const LIGHTROOM_TONE_CURVE_LINEAR LightroomToneCurveName = "Linear"
+const LIGHTROOM_TONE_CURVE_MEDIUM_CONTRAST LightroomToneCurveName = "Medium Contrast"
+const LIGHTROOM_TONE_CURVE_STRONG_CONTRAST LightroomToneCurveName = "Strong Contrast"
+const LIGHTROOM_TONE_CURVE_CUSTOM LightroomToneCurveName = "Custom"
+
+// The develop settings of a lightroom preset that the converter understands.
+// The `lightroom` tag contains the name of the setting in XMP files and catalogs.
+// Empty strings are used for enums that are not present in the preset.
+type LightroomDevelopSettings struct {
+	ProcessVersion    string                 `lightroom:"ProcessVersion"`
+	WhiteBalance      LightroomWhiteBalance  `lightroom:"WhiteBalance"`
+	ToneCurveName2012 LightroomToneCurveName `lightroom:"ToneCurveName2012"`
+	ToneCurveName     LightroomToneCurveName `lightroom:"ToneCurveName"`
+
+	Temperature LightroomSlider `lightroom:"Temperature"`
+	Tint        LightroomSlider `lightroom:"Tint"`
+
+	// Process version 2003 / 2010
+	Exposure   LightroomSlider `lightroom:"Exposure"`
+	Recovery   LightroomSlider `lightroom:"Recovery"`
+	FillLight  LightroomSlider `lightroom:"FillLight"`
+	Shadows    LightroomSlider `lightroom:"Shadows"`
+	Brightness LightroomSlider `lightroom:"Brightness"`
+	Contrast   LightroomSlider `lightroom:"Contrast"`
+	Clarity    LightroomSlider `lightroom:"Clarity"`
+
+	Exposure2012   LightroomSlider `lightroom:"Exposure2012"`
+	Contrast2012   LightroomSlider `lightroom:"Contrast2012"`
+	Highlights2012 LightroomSlider `lightroom:"Highlights2012"`
+	Shadows2012    LightroomSlider `lightroom:"Shadows2012"`
+	Whites2012     LightroomSlider `lightroom:"Whites2012"`
+	Blacks2012     LightroomSlider `lightroom:"Blacks2012"`
+	Texture        LightroomSlider `lightroom:"Texture"`
+	Clarity2012    LightroomSlider `lightroom:"Clarity2012"`
+	Dehaze         LightroomSlider `lightroom:"Dehaze"`
+	Vibrance       LightroomSlider `lightroom:"Vibrance"`
+	Saturation     LightroomSlider `lightroom:"Saturation"`
+
+	ParametricShadows        LightroomSlider `lightroom:"ParametricShadows"`
+	ParametricDarks          LightroomSlider `lightroom:"ParametricDarks"`
+	ParametricLights         LightroomSlider `lightroom:"ParametricLights"`
+	ParametricHighlights     LightroomSlider `lightroom:"ParametricHighlights"`
+	ParametricShadowSplit    LightroomSlider `lightroom:"ParametricShadowSplit"`
+	ParametricMidtoneSplit   LightroomSlider `lightroom:"ParametricMidtoneSplit"`
+	ParametricHighlightSplit LightroomSlider `lightroom:"ParametricHighlightSplit"`
+
+	HueAdjustmentRed     LightroomSlider `lightroom:"HueAdjustmentRed"`
+	HueAdjustmentOrange  LightroomSlider `lightroom:"HueAdjustmentOrange"`
+	HueAdjustmentYellow  LightroomSlider `lightroom:"HueAdjustmentYellow"`
+	HueAdjustmentGreen   LightroomSlider `lightroom:"HueAdjustmentGreen"`
+	HueAdjustmentAqua    LightroomSlider `lightroom:"HueAdjustmentAqua"`
+	HueAdjustmentBlue    LightroomSlider `lightroom:"HueAdjustmentBlue"`
+	HueAdjustmentPurple  LightroomSlider `lightroom:"HueAdjustmentPurple"`
+	HueAdjustmentMagenta LightroomSlider `lightroom:"HueAdjustmentMagenta"`
+
+	SaturationAdjustmentRed     LightroomSlider `lightroom:"SaturationAdjustmentRed"`
+	SaturationAdjustmentOrange  LightroomSlider `lightroom:"SaturationAdjustmentOrange"`
+	SaturationAdjustmentYellow  LightroomSlider `lightroom:"SaturationAdjustmentYellow"`
+	SaturationAdjustmentGreen   LightroomSlider `lightroom:"SaturationAdjustmentGreen"`
+	SaturationAdjustmentAqua    LightroomSlider `lightroom:"SaturationAdjustmentAqua"`
+	SaturationAdjustmentBlue    LightroomSlider `lightroom:"SaturationAdjustmentBlue"`
+	SaturationAdjustmentPurple  LightroomSlider `lightroom:"SaturationAdjustmentPurple"`
+	SaturationAdjustmentMagenta LightroomSlider `lightroom:"SaturationAdjustmentMagenta"`
+
+	LuminanceAdjustmentRed     LightroomSlider `lightroom:"LuminanceAdjustmentRed"`
+	LuminanceAdjustmentOrange  LightroomSlider `lightroom:"LuminanceAdjustmentOrange"`
+	LuminanceAdjustmentYellow  LightroomSlider `lightroom:"LuminanceAdjustmentYellow"`
+	LuminanceAdjustmentGreen   LightroomSlider `lightroom:"LuminanceAdjustmentGreen"`
+	LuminanceAdjustmentAqua    LightroomSlider `lightroom:"LuminanceAdjustmentAqua"`
+	LuminanceAdjustmentBlue    LightroomSlider `lightroom:"LuminanceAdjustmentBlue"`
+	LuminanceAdjustmentPurple  LightroomSlider `lightroom:"LuminanceAdjustmentPurple"`
+	LuminanceAdjustmentMagenta LightroomSlider `lightroom:"LuminanceAdjustmentMagenta"`
+
+	ConvertToGrayscale LightroomFlag `lightroom:"ConvertToGrayscale"`
+
+	GrayMixerRed     LightroomSlider `lightroom:"GrayMixerRed"`
+	GrayMixerOrange  LightroomSlider `lightroom:"GrayMixerOrange"`
+	GrayMixerYellow  LightroomSlider `lightroom:"GrayMixerYellow"`
+	GrayMixerGreen   LightroomSlider `lightroom:"GrayMixerGreen"`
+	GrayMixerAqua    LightroomSlider `lightroom:"GrayMixerAqua"`
+	GrayMixerBlue    LightroomSlider `lightroom:"GrayMixerBlue"`
+	GrayMixerPurple  LightroomSlider `lightroom:"GrayMixerPurple"`
+	GrayMixerMagenta LightroomSlider `lightroom:"GrayMixerMagenta"`
+
+	SplitToningShadowHue           LightroomSlider `lightroom:"SplitToningShadowHue"`
+	SplitToningShadowSaturation    LightroomSlider `lightroom:"SplitToningShadowSaturation"`
+	SplitToningHighlightHue        LightroomSlider `lightroom:"SplitToningHighlightHue"`
+	SplitToningHighlightSaturation LightroomSlider `lightroom:"SplitToningHighlightSaturation"`
+	SplitToningBalance             LightroomSlider `lightroom:"SplitToningBalance"`
+
+	ColorGradeMidtoneHue LightroomSlider `lightroom:"ColorGradeMidtoneHue"`
+	ColorGradeMidtoneSat LightroomSlider `lightroom:"ColorGradeMidtoneSat"`
+	ColorGradeBlending   LightroomSlider `lightroom:"ColorGradeBlending"`
+
+	Sharpness          LightroomSlider `lightroom:"Sharpness"`
+	SharpenRadius      LightroomSlider `lightroom:"SharpenRadius"`
+	SharpenDetail      LightroomSlider `lightroom:"SharpenDetail"`
+	SharpenEdgeMasking LightroomSlider `lightroom:"SharpenEdgeMasking"`
+
+	LuminanceSmoothing              LightroomSlider `lightroom:"LuminanceSmoothing"`
+	LuminanceNoiseReductionDetail   LightroomSlider `lightroom:"LuminanceNoiseReductionDetail"`
+	LuminanceNoiseReductionContrast LightroomSlider `lightroom:"LuminanceNoiseReductionContrast"`
+
+	ColorNoiseReduction           LightroomSlider `lightroom:"ColorNoiseReduction"`
+	ColorNoiseReductionDetail     LightroomSlider `lightroom:"ColorNoiseReductionDetail"`
+	ColorNoiseReductionSmoothness LightroomSlider `lightroom:"ColorNoiseReductionSmoothness"`
+
+	// Style is 1 (highlight priority), 2 (color priority) or 3 (paint overlay)
+	PostCropVignetteAmount            LightroomSlider `lightroom:"PostCropVignetteAmount"`
+	PostCropVignetteMidpoint          LightroomSlider `lightroom:"PostCropVignetteMidpoint"`
+	PostCropVignetteRoundness         LightroomSlider `lightroom:"PostCropVignetteRoundness"`
+	PostCropVignetteFeather           LightroomSlider `lightroom:"PostCropVignetteFeather"`
+	PostCropVignetteHighlightContrast LightroomSlider `lightroom:"PostCropVignetteHighlightContrast"`
+	PostCropVignetteStyle             LightroomSlider `lightroom:"PostCropVignetteStyle"`
+
+	VignetteAmount   LightroomSlider `lightroom:"VignetteAmount"`
+	VignetteMidpoint LightroomSlider `lightroom:"VignetteMidpoint"`
+
+	GrainAmount    LightroomSlider `lightroom:"GrainAmount"`
+	GrainSize      LightroomSlider `lightroom:"GrainSize"`
+	GrainFrequency LightroomSlider `lightroom:"GrainFrequency"`
+
+	// Lens corrections. LensProfileEnable and AutoLateralCA are 0 or 1.
+	LensProfileEnable          LightroomSlider `lightroom:"LensProfileEnable"`
+	AutoLateralCA              LightroomSlider `lightroom:"AutoLateralCA"`
+	LensManualDistortionAmount LightroomSlider `lightroom:"LensManualDistortionAmount"`
+	DefringePurpleAmount       LightroomSlider `lightroom:"DefringePurpleAmount"`
+	DefringePurpleHueLo        LightroomSlider `lightroom:"DefringePurpleHueLo"`
+	DefringePurpleHueHi        LightroomSlider `lightroom:"DefringePurpleHueHi"`
+	DefringeGreenAmount        LightroomSlider `lightroom:"DefringeGreenAmount"`
+	DefringeGreenHueLo         LightroomSlider `lightroom:"DefringeGreenHueLo"`
+	DefringeGreenHueHi         LightroomSlider `lightroom:"DefringeGreenHueHi"`
+
+	// Transform. PerspectiveUpright is the upright mode, 0 is off.
+	PerspectiveVertical   LightroomSlider `lightroom:"PerspectiveVertical"`
+	PerspectiveHorizontal LightroomSlider `lightroom:"PerspectiveHorizontal"`
+	PerspectiveRotate     LightroomSlider `lightroom:"PerspectiveRotate"`
+	PerspectiveScale      LightroomSlider `lightroom:"PerspectiveScale"`
+	PerspectiveAspect     LightroomSlider `lightroom:"PerspectiveAspect"`
+	PerspectiveX          LightroomSlider `lightroom:"PerspectiveX"`
+	PerspectiveY          LightroomSlider `lightroom:"PerspectiveY"`
+	PerspectiveUpright    LightroomSlider `lightroom:"PerspectiveUpright"`
+
+	// Crop of a photo. Coordinates are relative to the unrotated image as it is stored in the
+	// raw file (0 - 1), the angle is in degrees. CropConstrainToWarp is 0 or 1.
+	HasCrop             LightroomFlag   `lightroom:"HasCrop"`
+	CropTop             LightroomSlider `lightroom:"CropTop"`
+	CropLeft            LightroomSlider `lightroom:"CropLeft"`
+	CropBottom          LightroomSlider `lightroom:"CropBottom"`
+	CropRight           LightroomSlider `lightroom:"CropRight"`
+	CropAngle           LightroomSlider `lightroom:"CropAngle"`
+	CropConstrainToWarp LightroomSlider `lightroom:"CropConstrainToWarp"`
+
+	// Camera calibration
+	ShadowTint      LightroomSlider `lightroom:"ShadowTint"`
+	RedHue          LightroomSlider `lightroom:"RedHue"`
+	RedSaturation   LightroomSlider `lightroom:"RedSaturation"`
+	GreenHue        LightroomSlider `lightroom:"GreenHue"`
+	GreenSaturation LightroomSlider `lightroom:"GreenSaturation"`
+	BlueHue         LightroomSlider `lightroom:"BlueHue"`
+	BlueSaturation  LightroomSlider `lightroom:"BlueSaturation"`
+
+	ToneCurve LightroomCombinedToneCurve
+
+	// Tone curve of process version 2003 / 2010
+	LegacyToneCurve LightroomToneCurve
+}
+
+var lightroomSliderType = reflect.TypeOf(LightroomSlider{})
+var lightroomFlagType = reflect.TypeOf(LightroomFlag{})
+
+// Returns the field for the setting with the given lightroom name
+func (self *LightroomDevelopSettings) field(name string) (reflect.Value, bool) {
+	settings := reflect.ValueOf(self).Elem()
+	for index := 0; index < settings.NumField(); index++ {
+		if settings.Type().Field(index).Tag.Get("lightroom") == name {
+			return settings.Field(index), true
+		}
+	}
+
+	return reflect.Value{}, false
+}
+
+// Sets the setting with the given name from its XMP / catalog representation.
+// Returns false if the setting is not part of the typed settings.
+func (self *LightroomDevelopSettings) Set(name string, value string) (bool, error) {
+	field, exists := self.field(name)
+	if !exists {
+		return false, nil
+	}
+
+	switch field.Type() {
+	case lightroomSliderType:
+		number, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			return true, fmt.Errorf("could not convert %s='%s' to a number: %s", name, value, err)
+		}
+		field.Set(reflect.ValueOf(LightroomSlider{Value: number, Present: true}))
+
+	case lightroomFlagType:
+		flag, err := strconv.ParseBool(strings.ToLower(strings.TrimSpace(value)))
+		if err != nil {
+			return true, fmt.Errorf("could not convert %s='%s' to a boolean: %s", name, value, err)
+		}
+		field.Set(reflect.ValueOf(LightroomFlag{Value: flag, Present: true}))
+
+	default:
+		field.SetString(value)
+	}
+
+	return true, nil
+}
+
+// Resets the setting with the given name as if it had never been set.
+// Returns false if the setting is not part of the typed settings.
+func (self *LightroomDevelopSettings) Remove(name string) bool {
+	field, exists := self.field(name)
+	if exists {
+		field.Set(reflect.Zero(field.Type()))
+	}
+	return exists
+}
+
+// Calls the callback for every slider that is present, in the order of declaration
+func (self LightroomDevelopSettings) EachSlider(callback func(name string, slider LightroomSlider)) {
+	settings := reflect.ValueOf(self)
+	for index := 0; index < settings.NumField(); index++ {
+		slider, isSlider := settings.Field(index).Interface().(LightroomSlider)
+		if isSlider && slider.Present {
+			callback(settings.Type().Field(index).Tag.Get("lightroom"), slider)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package lib
+
+import (
+	"encoding/xml"
+	"reflect"
+	"testing"
+)
+
+func TestLightroomToneCurveUnmarshal(t *testing.T) {
+	tests := []struct {
+		xml      string
+		expected []LightroomToneCurvePoint
+	}{
+		{
+			`<crs:ToneCurvePV2012><rdf:Seq><rdf:li>0, 18</rdf:li><rdf:li>127.5, 140.25</rdf:li><rdf:li>255, 240</rdf:li></rdf:Seq></crs:ToneCurvePV2012>`,
+			[]LightroomToneCurvePoint{{0, 18}, {127.5, 140.25}, {255, 240}},
+		},
+		{
+			// Malformed points are skipped instead of becoming 0, 0
+			`<crs:ToneCurvePV2012><rdf:Seq><rdf:li>0, 0</rdf:li><rdf:li>64</rdf:li><rdf:li>a, 3</rdf:li><rdf:li>128, 140</rdf:li><rdf:li>255, 255</rdf:li></rdf:Seq></crs:ToneCurvePV2012>`,
+			[]LightroomToneCurvePoint{{0, 0}, {128, 140}, {255, 255}},
+		},
+	}
+
+	for _, test := range tests {
+		// Curves are always part of the description of a preset
+		description := struct {
+			Curve LightroomToneCurve `xml:"ToneCurvePV2012"`
+		}{}
+		err := xml.Unmarshal([]byte("<rdf:Description>"+test.xml+"</rdf:Description>"), &description)
+		if err != nil {
+			t.Fatal(err)
+		}
+		curve := description.Curve
+		if !reflect.DeepEqual(curve.Points, test.expected) {
+			t.Errorf("points of %s = %v, expected %v", test.xml, curve.Points, test.expected)
+		}
+	}
+}
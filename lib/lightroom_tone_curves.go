@@ -0,0 +1,52 @@
+package lib
+
+import (
+	"log"
+)
+
+// The built-in tone curves of lightroom by name. Presets that select one of them do not necessarily
+// contain its points. All process versions use the same points, the PV2003 / PV2010 curves are
+// applied on top of the legacy tone controls (which are translated separately).
+var lightroomNamedToneCurves = map[LightroomToneCurveName][]LightroomToneCurvePoint{
+	LIGHTROOM_TONE_CURVE_LINEAR: {
+		{In: 0, Out: 0},
+		{In: 255, Out: 255},
+	},
+	LIGHTROOM_TONE_CURVE_MEDIUM_CONTRAST: {
+		{In: 0, Out: 0},
+		{In: 32, Out: 22},
+		{In: 64, Out: 56},
+		{In: 128, Out: 128},
+		{In: 192, Out: 196},
+		{In: 255, Out: 255},
+	},
+	LIGHTROOM_TONE_CURVE_STRONG_CONTRAST: {
+		{In: 0, Out: 0},
+		{In: 32, Out: 16},
+		{In: 64, Out: 50},
+		{In: 128, Out: 128},
+		{In: 192, Out: 202},
+		{In: 255, Out: 255},
+	},
+}
+
+// Returns the settings with the named tone curve expanded into the points of the RGB curve, if the
+// preset names a built-in curve without containing its points. Expects settings that have been
+// upgraded to PV2012, which moves the legacy curve name to ToneCurveName2012.
+func (self LightroomDevelopSettings) WithNamedToneCurveExpanded() LightroomDevelopSettings {
+	name := self.ToneCurveName2012
+	if name == "" || name == LIGHTROOM_TONE_CURVE_CUSTOM || len(self.ToneCurve.Rgb.Points) > 0 {
+		return self
+	}
+
+	points, known := lightroomNamedToneCurves[name]
+	if !known {
+		log.Printf("[WARN] Unknown tone curve '%s' without points will be ignored.", name)
+		return self
+	}
+
+	log.Printf("[INFO] The preset uses the tone curve '%s' without its points, the built-in curve of lightroom is used.", name)
+	expanded := self
+	expanded.ToneCurve.Rgb = LightroomToneCurve{Points: append([]LightroomToneCurvePoint{}, points...)}
+	return expanded
+}
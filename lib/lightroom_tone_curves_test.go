@@ -0,0 +1,67 @@
+package lib
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Collects what is logged while running the function
+func captureLog(function func()) string {
+	output := bytes.Buffer{}
+	log.SetOutput(&output)
+	defer log.SetOutput(os.Stderr)
+
+	function()
+	return output.String()
+}
+
+func TestNamedToneCurveIsExpanded(t *testing.T) {
+	for _, processVersion := range []string{"10.0", "5.0", "6.7"} {
+		settings := LightroomDevelopSettings{}
+		settings.Set("ProcessVersion", processVersion)
+		settings.ToneCurveName2012 = LIGHTROOM_TONE_CURVE_MEDIUM_CONTRAST
+
+		expanded := settings.WithNamedToneCurveExpanded()
+		if !reflect.DeepEqual(expanded.ToneCurve.Rgb.Points, lightroomNamedToneCurves[LIGHTROOM_TONE_CURVE_MEDIUM_CONTRAST]) {
+			t.Errorf("process version %s: expected the medium contrast curve, got %v", processVersion, expanded.ToneCurve.Rgb.Points)
+		}
+
+		// The built-in curve must not be modified through the expanded settings
+		expanded.ToneCurve.Rgb.Points[1].Out = 0
+		if lightroomNamedToneCurves[LIGHTROOM_TONE_CURVE_MEDIUM_CONTRAST][1].Out == 0 {
+			t.Fatalf("expanding a named curve shares its points")
+		}
+	}
+}
+
+func TestNamedToneCurveKeepsPoints(t *testing.T) {
+	settings := LightroomDevelopSettings{}
+	settings.ToneCurveName2012 = LIGHTROOM_TONE_CURVE_STRONG_CONTRAST
+	settings.ToneCurve.Rgb.Points = []LightroomToneCurvePoint{{In: 0, Out: 10}, {In: 255, Out: 245}}
+
+	expanded := settings.WithNamedToneCurveExpanded()
+	if !reflect.DeepEqual(expanded.ToneCurve.Rgb.Points, settings.ToneCurve.Rgb.Points) {
+		t.Errorf("points of the preset were replaced: %v", expanded.ToneCurve.Rgb.Points)
+	}
+}
+
+func TestUnknownNamedToneCurveIsIgnored(t *testing.T) {
+	settings := LightroomDevelopSettings{}
+	settings.ToneCurveName2012 = "Very Strong Contrast"
+
+	var expanded LightroomDevelopSettings
+	output := captureLog(func() {
+		expanded = settings.WithNamedToneCurveExpanded()
+	})
+
+	if !strings.Contains(output, "[WARN] Unknown tone curve 'Very Strong Contrast'") {
+		t.Errorf("expected a warning about the unknown curve, got '%s'", output)
+	}
+	if expanded.ToneCurve.Rgb.Points != nil {
+		t.Errorf("expected no curve, got %#v", expanded.ToneCurve.Rgb.Points)
+	}
+}
@@ -0,0 +1,485 @@
+package lib
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+/*
+ * Minimal, read-only reader for SQLite 3 database files.
+ * Lightroom catalogs are SQLite databases. Since only a handful of tables have to be read,
+ * this reader only supports what is necessary for that instead of pulling in a full database driver:
+ *
+ *		- Walking table b-trees (interior and leaf pages) including overflow pages
+ *		- Decoding records into integers, floats, strings and blobs
+ *		- Column names from the `CREATE TABLE` statements in `sqlite_master`
+ *
+ * Indexes, WAL files and UTF-16 databases are not supported. The catalog must be closed in
+ * lightroom so that all changes have been written back into the main database file.
+ */
+
+const sqliteHeader = "SQLite format 3\x00"
+
+type sqliteDatabase struct {
+	file       *os.File
+	pageSize   int
+	usableSize int
+}
+
+type sqliteRow = map[string]interface{}
+
+func openSqliteDatabase(path string) (*sqliteDatabase, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, 100)
+	_, err = file.ReadAt(header, 0)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	if string(header[:16]) != sqliteHeader {
+		file.Close()
+		return nil, fmt.Errorf("%s is not a SQLite 3 database", path)
+	}
+	if binary.BigEndian.Uint32(header[56:60]) > 1 {
+		file.Close()
+		return nil, errors.New("only UTF-8 encoded SQLite databases are supported")
+	}
+
+	pageSize := int(binary.BigEndian.Uint16(header[16:18]))
+	if pageSize == 1 {
+		pageSize = 65536
+	}
+	if pageSize < 512 || pageSize-int(header[20]) < 480 {
+		file.Close()
+		return nil, fmt.Errorf("%s has an invalid page size", path)
+	}
+
+	return &sqliteDatabase{
+		file:       file,
+		pageSize:   pageSize,
+		usableSize: pageSize - int(header[20]),
+	}, nil
+}
+
+func (self *sqliteDatabase) Close() error {
+	return self.file.Close()
+}
+
+func (self *sqliteDatabase) page(number uint32) ([]byte, error) {
+	if number < 1 {
+		return nil, fmt.Errorf("invalid page number %d", number)
+	}
+
+	page := make([]byte, self.pageSize)
+	_, err := self.file.ReadAt(page, int64(number-1)*int64(self.pageSize))
+	return page, err
+}
+
+// Returns all rows of the given table with values keyed by column name.
+func (self *sqliteDatabase) rows(table string) ([]sqliteRow, error) {
+	master, err := self.records(1)
+	if err != nil {
+		return nil, err
+	}
+
+	// sqlite_master: type, name, tbl_name, rootpage, sql
+	for _, record := range master {
+		if len(record.values) < 5 || record.values[0] != "table" || !strings.EqualFold(fmt.Sprint(record.values[1]), table) {
+			continue
+		}
+
+		rootPage, _ := record.values[3].(int64)
+		sql, _ := record.values[4].(string)
+		columns, rowidColumn := sqliteColumnsFromCreateStatement(sql)
+
+		records, err := self.records(uint32(rootPage))
+		if err != nil {
+			return nil, err
+		}
+
+		rows := make([]sqliteRow, len(records))
+		for index, record := range records {
+			row := sqliteRow{}
+			for columnIndex, column := range columns {
+				// Records written before a column was added with ALTER TABLE do not contain it
+				if columnIndex < len(record.values) {
+					row[column.name] = record.values[columnIndex]
+				} else {
+					row[column.name] = column.defaultValue
+				}
+			}
+			if rowidColumn != "" {
+				row[rowidColumn] = record.rowid
+			}
+			rows[index] = row
+		}
+
+		return rows, nil
+	}
+
+	return nil, fmt.Errorf("table %s does not exist", table)
+}
+
+type sqliteRecord struct {
+	rowid  int64
+	values []interface{}
+}
+
+// B-trees of lightroom catalogs are a few levels deep. Deeper trees can only be the result of
+// pages that point back to their parents in a corrupt file.
+const sqliteMaxTreeDepth = 32
+
+// Walks the table b-tree starting at the given root page
+func (self *sqliteDatabase) records(pageNumber uint32) ([]sqliteRecord, error) {
+	return self.recordsOfTree(pageNumber, 0)
+}
+
+func (self *sqliteDatabase) recordsOfTree(pageNumber uint32, depth int) ([]sqliteRecord, error) {
+	if depth > sqliteMaxTreeDepth {
+		return nil, fmt.Errorf("page %d: b-tree is deeper than %d levels", pageNumber, sqliteMaxTreeDepth)
+	}
+
+	page, err := self.page(pageNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	// The first page contains the database header before the b-tree page header
+	headerOffset := 0
+	if pageNumber == 1 {
+		headerOffset = 100
+	}
+
+	pageType := page[headerOffset]
+	numberOfCells := int(binary.BigEndian.Uint16(page[headerOffset+3:]))
+
+	// Interior pages have a 12 byte header, leaf pages an 8 byte header. Both are followed by
+	// 2 byte pointers to the cells.
+	headerSize := 8
+	if pageType == 0x05 {
+		headerSize = 12
+	}
+	cellPointers := page[headerOffset+headerSize:]
+	if numberOfCells*2 > len(cellPointers) {
+		return nil, fmt.Errorf("page %d: %d cells do not fit into the page", pageNumber, numberOfCells)
+	}
+	cellOffset := func(cell int) (int, error) {
+		offset := int(binary.BigEndian.Uint16(cellPointers[cell*2:]))
+		if offset < headerOffset+headerSize || offset >= len(page) {
+			return 0, fmt.Errorf("page %d: cell %d is outside of the page", pageNumber, cell)
+		}
+		return offset, nil
+	}
+
+	switch pageType {
+	case 0x05:
+		// Interior table page: Cells point to the left children, the right most child is in the header
+		records := []sqliteRecord{}
+		for cell := 0; cell < numberOfCells; cell++ {
+			offset, err := cellOffset(cell)
+			if err != nil {
+				return nil, err
+			}
+			if offset+4 > len(page) {
+				return nil, fmt.Errorf("page %d: cell %d is truncated", pageNumber, cell)
+			}
+
+			children, err := self.recordsOfTree(binary.BigEndian.Uint32(page[offset:]), depth+1)
+			if err != nil {
+				return nil, err
+			}
+			records = append(records, children...)
+		}
+
+		children, err := self.recordsOfTree(binary.BigEndian.Uint32(page[headerOffset+8:]), depth+1)
+		if err != nil {
+			return nil, err
+		}
+		return append(records, children...), nil
+
+	case 0x0D:
+		records := make([]sqliteRecord, numberOfCells)
+		for cell := 0; cell < numberOfCells; cell++ {
+			offset, err := cellOffset(cell)
+			if err != nil {
+				return nil, err
+			}
+
+			records[cell], err = self.leafCell(page, offset)
+			if err != nil {
+				return nil, fmt.Errorf("page %d: cell %d: %s", pageNumber, cell, err)
+			}
+		}
+		return records, nil
+	}
+
+	return nil, fmt.Errorf("page %d is not a table b-tree page (type %d)", pageNumber, pageType)
+}
+
+func (self *sqliteDatabase) leafCell(page []byte, offset int) (sqliteRecord, error) {
+	payloadSize, read := sqliteVarint(page[offset:])
+	offset += read
+	rowid, read := sqliteVarint(page[offset:])
+	offset += read
+
+	if offset > len(page) || payloadSize > math.MaxInt32 {
+		return sqliteRecord{}, errors.New("cell is truncated")
+	}
+
+	payload, err := self.payload(page, offset, int(payloadSize))
+	if err != nil {
+		return sqliteRecord{}, err
+	}
+
+	values, err := sqliteDecodeRecord(payload)
+	return sqliteRecord{rowid: int64(rowid), values: values}, err
+}
+
+// Collects the payload of a cell. Large payloads are spilled into a linked list of overflow pages,
+// the size of the part stored locally is calculated as described in the file format documentation.
+func (self *sqliteDatabase) payload(page []byte, offset int, size int) ([]byte, error) {
+	maxLocal := self.usableSize - 35
+	if size <= maxLocal {
+		if offset+size > len(page) {
+			return nil, errors.New("payload is outside of the page")
+		}
+		return page[offset : offset+size], nil
+	}
+
+	minLocal := ((self.usableSize-12)*32)/255 - 23
+	local := minLocal + (size-minLocal)%(self.usableSize-4)
+	if local > maxLocal {
+		local = minLocal
+	}
+	if offset+local+4 > len(page) {
+		return nil, errors.New("payload is outside of the page")
+	}
+
+	payload := make([]byte, 0, size)
+	payload = append(payload, page[offset:offset+local]...)
+	overflowPage := binary.BigEndian.Uint32(page[offset+local:])
+
+	for len(payload) < size {
+		if overflowPage == 0 {
+			return nil, errors.New("overflow page chain ended prematurely")
+		}
+
+		overflow, err := self.page(overflowPage)
+		if err != nil {
+			return nil, err
+		}
+
+		chunk := size - len(payload)
+		if chunk > self.usableSize-4 {
+			chunk = self.usableSize - 4
+		}
+		payload = append(payload, overflow[4:4+chunk]...)
+		overflowPage = binary.BigEndian.Uint32(overflow)
+	}
+
+	return payload, nil
+}
+
+func sqliteDecodeRecord(payload []byte) ([]interface{}, error) {
+	headerSize, offset := sqliteVarint(payload)
+	if int(headerSize) > len(payload) {
+		return nil, errors.New("invalid record header")
+	}
+
+	serialTypes := []uint64{}
+	for offset < int(headerSize) && offset < len(payload) {
+		serialType, read := sqliteVarint(payload[offset:])
+		serialTypes = append(serialTypes, serialType)
+		offset += read
+	}
+
+	values := make([]interface{}, len(serialTypes))
+	body := payload[headerSize:]
+	for index, serialType := range serialTypes {
+		size := sqliteSerialTypeSize(serialType)
+		if size < 0 || size > len(body) {
+			return nil, errors.New("record is truncated")
+		}
+
+		values[index] = sqliteDecodeValue(serialType, body[:size])
+		body = body[size:]
+	}
+
+	return values, nil
+}
+
+func sqliteSerialTypeSize(serialType uint64) int {
+	switch serialType {
+	case 0, 8, 9:
+		return 0
+	case 1, 2, 3, 4:
+		return int(serialType)
+	case 5:
+		return 6
+	case 6, 7:
+		return 8
+	}
+
+	if serialType >= 12 {
+		return int(serialType-12) / 2
+	}
+	return 0
+}
+
+func sqliteDecodeValue(serialType uint64, data []byte) interface{} {
+	switch serialType {
+	case 0:
+		return nil
+	case 1, 2, 3, 4, 5, 6:
+		// Big endian two's complement integers of varying sizes
+		value := int64(int8(data[0]))
+		for _, b := range data[1:] {
+			value = value<<8 | int64(b)
+		}
+		return value
+	case 7:
+		return math.Float64frombits(binary.BigEndian.Uint64(data))
+	case 8:
+		return int64(0)
+	case 9:
+		return int64(1)
+	}
+
+	if serialType%2 == 0 {
+		return append([]byte{}, data...)
+	}
+	return string(data)
+}
+
+// SQLite variable length integers: Up to 9 bytes, the first 8 contribute 7 bits each,
+// the 9th byte contributes all 8 bits.
+func sqliteVarint(data []byte) (uint64, int) {
+	var value uint64
+	for index := 0; index < 8 && index < len(data); index++ {
+		value = value<<7 | uint64(data[index]&0x7f)
+		if data[index]&0x80 == 0 {
+			return value, index + 1
+		}
+	}
+
+	if len(data) < 9 {
+		return value, len(data)
+	}
+	return value<<8 | uint64(data[8]), 9
+}
+
+type sqliteColumn struct {
+	name         string
+	defaultValue interface{}
+}
+
+// Extracts the columns from a `CREATE TABLE` statement. Also returns the name of the
+// `INTEGER PRIMARY KEY` column (if any) as that column is an alias for the rowid and stored as NULL.
+func sqliteColumnsFromCreateStatement(sql string) ([]sqliteColumn, string) {
+	start := strings.Index(sql, "(")
+	end := strings.LastIndex(sql, ")")
+	if start == -1 || end <= start {
+		return nil, ""
+	}
+
+	definitions := []string{}
+	depth := 0
+	quoted := false
+	current := strings.Builder{}
+	for _, character := range sql[start+1 : end] {
+		switch {
+		case character == '\'':
+			quoted = !quoted
+		case quoted:
+		case character == '(':
+			depth++
+		case character == ')':
+			depth--
+		case character == ',' && depth == 0:
+			definitions = append(definitions, current.String())
+			current.Reset()
+			continue
+		}
+		current.WriteRune(character)
+	}
+	definitions = append(definitions, current.String())
+
+	columns := []sqliteColumn{}
+	rowidColumn := ""
+	for _, definition := range definitions {
+		parts := strings.Fields(definition)
+		if len(parts) == 0 {
+			continue
+		}
+
+		switch strings.ToUpper(parts[0]) {
+		case "PRIMARY", "UNIQUE", "CHECK", "FOREIGN", "CONSTRAINT":
+			continue
+		}
+
+		name := strings.Trim(parts[0], "\"`[]'")
+		columns = append(columns, sqliteColumn{name: name, defaultValue: sqliteColumnDefault(definition)})
+
+		if strings.Contains(strings.ToUpper(definition), "INTEGER PRIMARY KEY") {
+			rowidColumn = name
+		}
+	}
+
+	return columns, rowidColumn
+}
+
+var sqliteDefaultClause = regexp.MustCompile(`(?i)\sDEFAULT\b`)
+
+// Literal value of the `DEFAULT` clause of a column definition. Expressions other than
+// literals (e.g. CURRENT_TIMESTAMP) are not evaluated and result in NULL.
+func sqliteColumnDefault(definition string) interface{} {
+	match := sqliteDefaultClause.FindStringIndex(definition)
+	if match == nil {
+		return nil
+	}
+
+	value := strings.TrimSpace(definition[match[1]:])
+	for strings.HasPrefix(value, "(") {
+		closing := strings.LastIndex(value, ")")
+		if closing == -1 {
+			return nil
+		}
+		value = strings.TrimSpace(value[1:closing])
+	}
+
+	if strings.HasPrefix(value, "'") {
+		closing := strings.Index(strings.ReplaceAll(value[1:], "''", "  "), "'")
+		if closing == -1 {
+			return nil
+		}
+		return strings.ReplaceAll(value[1:closing+1], "''", "'")
+	}
+
+	literal := strings.Fields(value)
+	if len(literal) == 0 {
+		return nil
+	}
+	if integer, err := strconv.ParseInt(literal[0], 10, 64); err == nil {
+		return integer
+	}
+	if float, err := strconv.ParseFloat(literal[0], 64); err == nil {
+		return float
+	}
+	switch strings.ToUpper(literal[0]) {
+	case "TRUE":
+		return int64(1)
+	case "FALSE":
+		return int64(0)
+	}
+	return nil
+}
@@ -0,0 +1,225 @@
+package lib
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSqliteVarint(t *testing.T) {
+	tests := []struct {
+		data  []byte
+		value uint64
+		read  int
+	}{
+		{[]byte{0x00}, 0, 1},
+		{[]byte{0x7f}, 127, 1},
+		{[]byte{0x81, 0x00}, 128, 2},
+		{[]byte{0xff, 0x7f}, 16383, 2},
+		{[]byte{0x81, 0x80, 0x00}, 16384, 3},
+		{[]byte{0xfa, 0x89, 0x00}, 2000000, 3},
+		// Trailing bytes are not part of the varint
+		{[]byte{0x05, 0xff, 0xff}, 5, 1},
+		// The 9th byte contributes all 8 bits
+		{[]byte{0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0xff}, 0xff, 9},
+		{[]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, 0xffffffffffffffff, 9},
+		{[]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01}, 0xffffffffffffffff, 9},
+	}
+
+	for _, test := range tests {
+		value, read := sqliteVarint(test.data)
+		if value != test.value || read != test.read {
+			t.Errorf("sqliteVarint(% x) = %d, %d; expected %d, %d", test.data, value, read, test.value, test.read)
+		}
+	}
+}
+
+func TestSqliteDecodeRecord(t *testing.T) {
+	// Header of 6 bytes: NULL, 1 byte integer, the constant 1, a string of 2 bytes and a float
+	payload := []byte{0x06, 0x00, 0x01, 0x09, 0x11, 0x07, 0xfe, 'a', 'b', 0x3f, 0xf8, 0, 0, 0, 0, 0, 0}
+	values, err := sqliteDecodeRecord(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []interface{}{nil, int64(-2), int64(1), "ab", 1.5}
+	if len(values) != len(expected) {
+		t.Fatalf("expected %d values, got %v", len(expected), values)
+	}
+	for index := range expected {
+		if values[index] != expected[index] {
+			t.Errorf("value %d = %#v, expected %#v", index, values[index], expected[index])
+		}
+	}
+
+	if _, err := sqliteDecodeRecord(payload[:8]); err == nil {
+		t.Errorf("expected an error for a truncated record")
+	}
+}
+
+func TestSqliteReadsInteriorAndOverflowPages(t *testing.T) {
+	database, err := openSqliteDatabase(testCatalog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close()
+
+	// The fixture uses 512 byte pages, so the tables span multiple leaf pages
+	// and the snapshot text does not fit into a single cell.
+	if database.pageSize != 512 {
+		t.Fatalf("expected the fixture to use 512 byte pages, got %d", database.pageSize)
+	}
+
+	files, err := database.rows("AgLibraryFile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 80 {
+		t.Errorf("expected 80 files, got %d", len(files))
+	}
+	last := files[len(files)-1]
+	if last["id_local"] != int64(2000000) || last["idx_filename"] != "IMG_0001.CR2" {
+		t.Errorf("unexpected last file %v", last)
+	}
+
+	snapshots, err := database.rows("Adobe_libraryImageDevelopSnapshot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snapshots) != 3 {
+		t.Fatalf("expected 3 snapshots, got %d", len(snapshots))
+	}
+
+	text, _ := snapshots[0]["text"].(string)
+	if len(text) <= database.usableSize {
+		t.Fatalf("snapshot text with %d bytes does not use overflow pages", len(text))
+	}
+	if !strings.HasPrefix(text, "s = {") || !strings.HasSuffix(text, "}\n") {
+		t.Errorf("overflowing snapshot text is corrupted")
+	}
+	if snapshots[0]["image"] != int64(3000000) || snapshots[0]["name"] != "Final" {
+		t.Errorf("unexpected snapshot %v", snapshots[0])
+	}
+
+	if _, err := database.rows("DoesNotExist"); err == nil {
+		t.Errorf("expected an error for a missing table")
+	}
+}
+
+func TestSqliteUsesDefaultsOfAddedColumns(t *testing.T) {
+	database, err := openSqliteDatabase(testCatalog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close()
+
+	// orientation (without a default) and pick (DEFAULT (0)) were added with ALTER TABLE,
+	// the rows written before do not contain them
+	images, err := database.rows("Adobe_images")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, image := range images {
+		if image["pick"] != int64(0) {
+			t.Errorf("image %v: pick = %#v, expected the default 0", image["id_local"], image["pick"])
+		}
+		if image["id_local"] == int64(1) && image["orientation"] != nil {
+			t.Errorf("image 1: orientation = %#v, expected NULL", image["orientation"])
+		}
+		if image["id_local"] == int64(3000000) && image["orientation"] != "BC" {
+			t.Errorf("image 3000000: orientation = %#v, expected 'BC'", image["orientation"])
+		}
+	}
+}
+
+// Corrupt files have to result in errors (or garbage), but never in a panic
+func TestSqliteCorruptPages(t *testing.T) {
+	contents, err := ioutil.ReadFile(testCatalog)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const pageSize = 512
+	path := filepath.Join(t.TempDir(), "Corrupt.lrcat")
+	corruptions := []struct {
+		offset int
+		data   []byte
+	}{
+		// Number of cells, first cell pointer, right most child of interior pages
+		{3, []byte{0xff, 0xff}},
+		{8, []byte{0xff, 0xff}},
+		{8, []byte{0x00, 0x01}},
+		{12, []byte{0x01, 0xf0}},
+		{8, []byte{0x7f, 0xff, 0xff, 0xff}},
+	}
+
+	errors := 0
+	for page := 1; page*pageSize <= len(contents); page++ {
+		for _, corruption := range corruptions {
+			corrupt := append([]byte{}, contents...)
+			offset := (page-1)*pageSize + corruption.offset
+			if page == 1 {
+				offset += 100
+			}
+			copy(corrupt[offset:], corruption.data)
+
+			err := ioutil.WriteFile(path, corrupt, 0644)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			func() {
+				defer func() {
+					if recovered := recover(); recovered != nil {
+						t.Errorf("page %d, offset %d: %v", page, corruption.offset, recovered)
+					}
+				}()
+
+				database, err := openSqliteDatabase(path)
+				if err != nil {
+					errors++
+					return
+				}
+				defer database.Close()
+
+				for _, table := range []string{"AgLibraryFile", "Adobe_images", "Adobe_imageDevelopSettings", "Adobe_libraryImageDevelopSnapshot"} {
+					if _, err := database.rows(table); err != nil {
+						errors++
+					}
+				}
+			}()
+		}
+	}
+
+	if errors == 0 {
+		t.Errorf("no corruption was detected")
+	}
+}
+
+func TestSqliteColumnsFromCreateStatement(t *testing.T) {
+	columns, rowid := sqliteColumnsFromCreateStatement(
+		`CREATE TABLE Adobe_images (id_local INTEGER PRIMARY KEY, "copyName" DEFAULT 'it''s, (a) copy', pick NOT NULL DEFAULT (0), ` +
+			`rating DEFAULT -1.5, touched DEFAULT CURRENT_TIMESTAMP, flag NOT NULL DEFAULT(TRUE), UNIQUE (id_local, pick))`,
+	)
+
+	expected := []sqliteColumn{
+		{"id_local", nil},
+		{"copyName", "it's, (a) copy"},
+		{"pick", int64(0)},
+		{"rating", -1.5},
+		{"touched", nil},
+		{"flag", int64(1)},
+	}
+	if len(columns) != len(expected) {
+		t.Fatalf("columns = %+v, expected %+v", columns, expected)
+	}
+	for index := range expected {
+		if columns[index] != expected[index] {
+			t.Errorf("column %d = %#v, expected %#v", index, columns[index], expected[index])
+		}
+	}
+	if rowid != "id_local" {
+		t.Errorf("rowid column = '%s', expected 'id_local'", rowid)
+	}
+}